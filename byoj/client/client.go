@@ -0,0 +1,335 @@
+// Byitter REST 接口的 Go 客户端，方法与 /v1 下的路由一一对应
+// 登录后自动携带并刷新令牌，5xx 响应按指数退避重试，带游标的列表接口通过 Iterator 逐页获取
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	apiPrefix = "/v1"
+	// access token 在到期前这段时间内即提前刷新
+	refreshMargin = 30 * time.Second
+)
+
+// 登录或刷新得到的令牌，与 /v1/user/login 的响应一致，可直接以 JSON 保存
+type Tokens struct {
+	ID                   uint32 `json:"user_id"`
+	UserName             string `json:"user_name"`
+	AccessToken          string `json:"access_token"`
+	AccessTokenExpireAt  int64  `json:"access_token_expiration_time"`
+	RefreshToken         string `json:"refresh_token"`
+	RefreshTokenExpireAt int64  `json:"refresh_token_expiration_time"`
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// 只返回状态文本的接口的响应
+type statusMessage struct {
+	Status string `json:"status"`
+}
+
+type Client struct {
+	// 5xx 响应的最大重试次数，为 0 时不重试
+	MaxRetries int
+	// 首次重试前的等待时间，之后每次翻倍并加入随机抖动
+	RetryBackoff time.Duration
+	// 请求头 Accept-Language，为空时使用服务端的默认语言
+	Language string
+	// 自动刷新令牌后调用，可用于保存新的令牌
+	OnTokenRefresh func(Tokens)
+
+	baseURL    string
+	httpClient *http.Client
+
+	mu     sync.Mutex
+	tokens *Tokens
+	// 进行中的刷新，完成时关闭
+	refreshing chan struct{}
+}
+
+/**
+ * 创建客户端
+ * @param: baseURL 服务地址，如 "http://127.0.0.1:3435"，请求路径自动加上 /v1
+ * @param: httpClient 为 nil 时使用 http.DefaultClient
+ **/
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		MaxRetries:   3,
+		RetryBackoff: 200 * time.Millisecond,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+		httpClient:   httpClient,
+	}
+}
+
+// 当前的令牌，未登录时返回 nil
+func (c *Client) Tokens() *Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		return nil
+	}
+	tokens := *c.tokens
+	return &tokens
+}
+
+// 使用已保存的令牌，为 nil 时清除令牌
+func (c *Client) SetTokens(tokens *Tokens) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if tokens == nil {
+		c.tokens = nil
+		return
+	}
+	saved := *tokens
+	c.tokens = &saved
+}
+
+/**
+ * 调用接口，已登录时携带 access token
+ * 令牌即将到期时先刷新；接口返回 401 时刷新令牌并重试一次，refresh token 同样失效时返回原错误
+ * @param: query 查询字符串，可为 nil
+ * @param: body 以 JSON 发送的请求体，为 nil 时不发送
+ * @param: data 成功时解析 data 字段的目标，可为 nil
+ **/
+func (c *Client) call(ctx context.Context, method string, path string, query url.Values, body interface{}, data interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(ctx, method, path, query, payload, token, data)
+	var apiErr *Error
+	if token != "" && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		refreshed, rerr := c.refresh(ctx, token)
+		if rerr == nil {
+			return c.send(ctx, method, path, query, payload, refreshed, data)
+		}
+	}
+	return err
+}
+
+// 当前可用的 access token，即将到期时先刷新；未登录时返回空字符串
+func (c *Client) accessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	tokens := c.tokens
+	c.mu.Unlock()
+	if tokens == nil {
+		return "", nil
+	}
+	if time.Unix(tokens.AccessTokenExpireAt, 0).After(time.Now().Add(refreshMargin)) {
+		return tokens.AccessToken, nil
+	}
+	token, err := c.refresh(ctx, tokens.AccessToken)
+	if err != nil {
+		// refresh token 已失效时仍发送原令牌，由服务端返回具体错误
+		return tokens.AccessToken, nil
+	}
+	return token, nil
+}
+
+var errNoRefreshToken = errors.New("client: no valid refresh token")
+
+/**
+ * 刷新令牌，并发调用时只刷新一次
+ * 请求及 OnTokenRefresh 均在锁外进行，回调中可以调用 Tokens 及 SetTokens
+ * @param: stale 调用方持有的 access token，已被其他调用刷新时直接返回新的令牌
+ **/
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.mu.Lock()
+	for c.refreshing != nil && c.tokens != nil && c.tokens.AccessToken == stale {
+		// 等待进行中的刷新完成
+		done := c.refreshing
+		c.mu.Unlock()
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-done:
+		}
+		c.mu.Lock()
+	}
+	if c.tokens == nil {
+		c.mu.Unlock()
+		return "", errNoRefreshToken
+	}
+	if c.tokens.AccessToken != stale {
+		token := c.tokens.AccessToken
+		c.mu.Unlock()
+		return token, nil
+	}
+	if c.tokens.RefreshToken == "" || !time.Unix(c.tokens.RefreshTokenExpireAt, 0).After(time.Now()) {
+		c.mu.Unlock()
+		return "", errNoRefreshToken
+	}
+	refreshToken := c.tokens.RefreshToken
+	done := make(chan struct{})
+	c.refreshing = done
+	c.mu.Unlock()
+
+	var tokens Tokens
+	payload, err := json.Marshal(refreshRequest{RefreshToken: refreshToken})
+	if err == nil {
+		err = c.send(ctx, http.MethodPost, "/user/refresh", nil, payload, "", &tokens)
+	}
+
+	c.mu.Lock()
+	c.refreshing = nil
+	close(done)
+	// 刷新期间调用过 SetTokens 时保留其设置的令牌
+	current := c.tokens != nil && c.tokens.RefreshToken == refreshToken
+	var apiErr *Error
+	if current && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		// refresh token 已被服务端拒绝，之后不再尝试刷新
+		c.tokens.RefreshToken = ""
+	}
+	if err != nil {
+		c.mu.Unlock()
+		return "", err
+	}
+	var onRefresh func(Tokens)
+	if current {
+		saved := tokens
+		c.tokens = &saved
+		onRefresh = c.OnTokenRefresh
+	}
+	c.mu.Unlock()
+
+	if onRefresh != nil {
+		onRefresh(tokens)
+	}
+	return tokens.AccessToken, nil
+}
+
+// 发送请求，按 retryable 的规则重试
+func (c *Client) send(ctx context.Context, method string, path string, query url.Values, payload []byte, token string, data interface{}) error {
+	backoff := c.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := c.sendOnce(ctx, method, path, query, payload, token, data)
+		if !retry || attempt >= c.MaxRetries {
+			return err
+		}
+
+		wait := backoff
+		if wait > 0 {
+			wait = wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+/**
+ * 发送一次请求
+ * GET 请求在 5xx 及网络错误时重试；其他请求可能已被处理，只在 502、503、504 时重试，避免重复发帖等
+ **/
+func (c *Client) sendOnce(ctx context.Context, method string, path string, query url.Values, payload []byte, token string, data interface{}) (retry bool, err error) {
+	target := c.baseURL + apiPrefix + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return false, err
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if c.Language != "" {
+		req.Header.Set("Accept-Language", c.Language)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return method == http.MethodGet && ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&envelope)
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		// 网关等返回的非 JSON 响应只保留状态码
+		var message errorMessage
+		if decodeErr == nil && json.Unmarshal(envelope.Data, &message) == nil && message.Code != "" {
+			apiErr.Code = message.Code
+			apiErr.Message = message.Message
+			apiErr.RequestID = message.RequestID
+			apiErr.Details = message.Details
+		}
+		if apiErr.RequestID == "" {
+			apiErr.RequestID = resp.Header.Get("X-Request-ID")
+		}
+		return retryable(method, resp.StatusCode), apiErr
+	}
+	if decodeErr != nil {
+		return false, decodeErr
+	}
+	if data != nil {
+		return false, json.Unmarshal(envelope.Data, data)
+	}
+	return false, nil
+}
+
+func retryable(method string, status int) bool {
+	if status < http.StatusInternalServerError {
+		return false
+	}
+	if method == http.MethodGet {
+		return true
+	}
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+func (c *Client) get(ctx context.Context, path string, query url.Values, data interface{}) error {
+	return c.call(ctx, http.MethodGet, path, query, nil, data)
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, data interface{}) error {
+	return c.call(ctx, http.MethodPost, path, nil, body, data)
+}
+
+// 只返回状态文本的接口
+func (c *Client) status(ctx context.Context, path string, body interface{}) (string, error) {
+	var status statusMessage
+	err := c.post(ctx, path, body, &status)
+	return status.Status, err
+}
@@ -0,0 +1,270 @@
+package client_test
+
+import (
+	"byoj/client"
+	"byoj/controllers"
+	"byoj/router"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+/**
+ * 启动连接真实路由的测试服务
+ * @param: intercept 在路由之前处理请求，返回 true 表示已写入响应；用于模拟需要数据库的接口
+ **/
+func newServer(t *testing.T, intercept func(w http.ResponseWriter, r *http.Request) bool) *client.Client {
+	e := echo.New()
+	router.Load(e)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if intercept != nil && intercept(w, r) {
+			return
+		}
+		e.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	c := client.New(server.URL, server.Client())
+	c.RetryBackoff = time.Millisecond
+	return c
+}
+
+func writeData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(controllers.ResponseStruct{Code: status, Message: http.StatusText(status), Data: data})
+}
+
+func TestErrors(t *testing.T) {
+	c := newServer(t, nil)
+	ctx := context.Background()
+
+	cases := []struct {
+		lang   string
+		msg    string
+		detail string
+	}{
+		{"", "Invalid parameter.", "user_name is required."},
+		{"zh", "参数无效。", "user_name 为必填项。"},
+	}
+	for _, tc := range cases {
+		c.Language = tc.lang
+		_, err := c.Register(ctx, client.RegisterRequest{})
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("Register error = %v, want *client.Error", err)
+		}
+		if apiErr.StatusCode != http.StatusBadRequest || !client.IsCode(err, "invalid_parameter") || apiErr.Message != tc.msg || apiErr.RequestID == "" {
+			t.Errorf("lang %q: error = %+v", tc.lang, apiErr)
+		}
+		found := false
+		for _, d := range apiErr.Details {
+			if d.Field == "user_name" {
+				found = d.Message == tc.detail
+			}
+		}
+		if !found {
+			t.Errorf("lang %q: details = %+v, want user_name %q", tc.lang, apiErr.Details, tc.detail)
+		}
+	}
+
+	// 真实的刷新接口拒绝无效的 refresh token
+	c.SetTokens(&client.Tokens{AccessToken: "a", RefreshToken: "not-a-jwt", RefreshTokenExpireAt: time.Now().Add(time.Hour).Unix()})
+	if _, err := c.Refresh(ctx); !client.IsCode(err, "unauthorized") {
+		t.Errorf("Refresh with an invalid token = %v, want unauthorized", err)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	c := newServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[r.Method]++
+		switch r.Method {
+		case http.MethodGet:
+			// 前两次失败，第三次交给真实路由
+			if attempts[r.Method] <= 2 {
+				writeData(w, http.StatusServiceUnavailable, nil)
+				return true
+			}
+		case http.MethodPost:
+			writeData(w, http.StatusInternalServerError, nil)
+			return true
+		}
+		return false
+	})
+	ctx := context.Background()
+
+	// 未指定用户时真实路由返回 invalid_parameter
+	_, err := c.User(ctx, client.UserQuery{})
+	if !client.IsCode(err, "invalid_parameter") || attempts[http.MethodGet] != 3 {
+		t.Errorf("User = %v after %d attempts, want invalid_parameter after 3", err, attempts[http.MethodGet])
+	}
+
+	// POST 的 500 可能已被处理，不重试
+	_, err = c.Like(ctx, 1)
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError || attempts[http.MethodPost] != 1 {
+		t.Errorf("Like = %v after %d attempts, want 500 after 1", err, attempts[http.MethodPost])
+	}
+
+	c.MaxRetries = 0
+	attempts[http.MethodGet] = 0
+	if _, err := c.User(ctx, client.UserQuery{ID: 1}); err == nil || attempts[http.MethodGet] != 1 {
+		t.Errorf("User with MaxRetries 0 = %v after %d attempts", err, attempts[http.MethodGet])
+	}
+}
+
+func TestTokenRefresh(t *testing.T) {
+	var mu sync.Mutex
+	refreshes := 0
+	var authorizations []string
+	c := newServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/v1/user/refresh":
+			refreshes++
+			var req controllers.UserRefreshRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.RefreshToken != "refresh-"+strconv.Itoa(refreshes-1) {
+				writeData(w, http.StatusUnauthorized, controllers.ErrorMessage{Code: "unauthorized"})
+				return true
+			}
+			writeData(w, http.StatusOK, client.Tokens{
+				AccessToken:          "access-" + strconv.Itoa(refreshes),
+				AccessTokenExpireAt:  time.Now().Add(time.Hour).Unix(),
+				RefreshToken:         "refresh-" + strconv.Itoa(refreshes),
+				RefreshTokenExpireAt: time.Now().Add(time.Hour).Unix(),
+			})
+			return true
+		case "/v1/user/isauth":
+			// 交给真实路由，测试用的令牌总是返回 401
+			authorizations = append(authorizations, r.Header.Get("Authorization"))
+		}
+		return false
+	})
+	var saved []client.Tokens
+	c.OnTokenRefresh = func(tokens client.Tokens) {
+		// 回调在锁外调用，可以读取当前令牌
+		if current := c.Tokens(); current == nil || current.AccessToken != tokens.AccessToken {
+			t.Errorf("Tokens in OnTokenRefresh = %+v, want %q", current, tokens.AccessToken)
+		}
+		saved = append(saved, tokens)
+	}
+	ctx := context.Background()
+
+	// access token 已过期，请求前先刷新；服务端仍返回 401 时再刷新并重试一次
+	c.SetTokens(&client.Tokens{
+		AccessToken:          "access-0",
+		AccessTokenExpireAt:  time.Now().Add(-time.Minute).Unix(),
+		RefreshToken:         "refresh-0",
+		RefreshTokenExpireAt: time.Now().Add(time.Hour).Unix(),
+	})
+	err := c.IsAuth(ctx)
+	if !client.IsCode(err, "unauthorized") {
+		t.Errorf("IsAuth = %v, want unauthorized", err)
+	}
+	want := []string{"Bearer access-1", "Bearer access-2"}
+	if len(authorizations) != len(want) || authorizations[0] != want[0] || authorizations[1] != want[1] {
+		t.Errorf("Authorization headers = %q, want %q", authorizations, want)
+	}
+	if refreshes != 2 || len(saved) != 2 || c.Tokens().AccessToken != "access-2" {
+		t.Errorf("refreshes = %d, saved = %d, token = %q", refreshes, len(saved), c.Tokens().AccessToken)
+	}
+
+	// refresh token 被拒绝后不再刷新
+	c.SetTokens(&client.Tokens{
+		AccessToken:          "stale",
+		AccessTokenExpireAt:  time.Now().Add(time.Hour).Unix(),
+		RefreshToken:         "revoked",
+		RefreshTokenExpireAt: time.Now().Add(time.Hour).Unix(),
+	})
+	refreshes = 0
+	for i := 0; i < 2; i++ {
+		if err := c.IsAuth(ctx); !client.IsCode(err, "unauthorized") {
+			t.Errorf("IsAuth with a revoked refresh token = %v, want unauthorized", err)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("refreshes with a revoked refresh token = %d, want 1", refreshes)
+	}
+}
+
+func TestIterators(t *testing.T) {
+	c := newServer(t, func(w http.ResponseWriter, r *http.Request) bool {
+		switch r.URL.Path {
+		case "/v1/post/bookmarks":
+			// 三页，每页两个帖子
+			cursor, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+			resp := controllers.BookmarkGetResponse{}
+			for id := cursor + 1; id <= cursor+2; id++ {
+				resp.PostList = append(resp.PostList, controllers.PostResponse{PostID: uint32(id)})
+			}
+			if cursor < 4 {
+				resp.NextCursor = strconv.Itoa(cursor + 2)
+			}
+			writeData(w, http.StatusOK, resp)
+			return true
+		case "/v1/post":
+			// 帖子 5 到 1，按 next_cursor 翻页；帖子 4 命中屏蔽词，所在的页不足 limit
+			if r.URL.Query().Get("order_by") != "time" {
+				t.Errorf("Timeline order_by = %q", r.URL.Query().Get("order_by"))
+			}
+			cursor, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+			if cursor == 0 {
+				cursor = 6
+			}
+			limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+			resp := controllers.PostGetResponse{}
+			last := cursor
+			for id := cursor - 1; id >= 1 && cursor-id <= limit; id-- {
+				if id != 4 {
+					resp.PostList = append(resp.PostList, controllers.PostResponse{PostID: uint32(id)})
+				}
+				last = id
+			}
+			if last > 1 {
+				resp.NextCursor = strconv.Itoa(last)
+			}
+			writeData(w, http.StatusOK, resp)
+			return true
+		}
+		return false
+	})
+	ctx := context.Background()
+
+	var ids []uint32
+	it := c.Bookmarks(2)
+	for it.Next(ctx) {
+		ids = append(ids, it.Value().PostID)
+	}
+	if it.Err() != nil || len(ids) != 6 || ids[0] != 1 || ids[5] != 6 {
+		t.Errorf("Bookmarks = %v, %v", ids, it.Err())
+	}
+
+	ids = nil
+	it = c.Timeline(client.PostsQuery{Limit: 2})
+	for it.Next(ctx) {
+		ids = append(ids, it.Value().PostID)
+	}
+	if it.Err() != nil || len(ids) != 4 || ids[0] != 5 || ids[1] != 3 || ids[3] != 1 {
+		t.Errorf("Timeline = %v, %v", ids, it.Err())
+	}
+
+	// 错误通过 Err 返回
+	it = c.SearchPosts("", 0)
+	if it.Next(ctx) || !client.IsCode(it.Err(), "invalid_parameter") {
+		t.Errorf("SearchPosts with an empty query: err = %v", it.Err())
+	}
+}
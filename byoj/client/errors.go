@@ -0,0 +1,57 @@
+package client
+
+import (
+	"errors"
+	"strconv"
+)
+
+// 字段级错误，Rule 为违反的规则，Limit 为规则的限制值
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"msg"`
+	Limit   int    `json:"limit,omitempty"`
+	Actual  int    `json:"actual,omitempty"`
+}
+
+// 错误响应的 data 字段
+type errorMessage struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"msg"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id"`
+}
+
+/**
+ * 接口返回的错误
+ * Code 为错误码目录中稳定的字符串，应据此判断错误类型；Message 按 Language 翻译，可能调整
+ * 网关等返回非 JSON 响应时 Code 为空
+ **/
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	Details    []FieldError
+}
+
+func (e *Error) Error() string {
+	msg := "byitter: " + strconv.Itoa(e.StatusCode)
+	if e.Code != "" {
+		msg += " " + e.Code
+	}
+	msg += ": " + e.Message
+	for _, detail := range e.Details {
+		msg += "; " + detail.Message
+	}
+	if e.RequestID != "" {
+		msg += " (request_id " + e.RequestID + ")"
+	}
+	return msg
+}
+
+// err 是否为接口返回的指定错误码，如 "user_not_found"
+func IsCode(err error, code string) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
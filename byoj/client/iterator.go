@@ -0,0 +1,59 @@
+package client
+
+import "context"
+
+/**
+ * 逐页获取的列表，Next 在当前页取完后请求下一页
+ * 用法：
+ *	it := c.Bookmarks(20)
+ *	for it.Next(ctx) {
+ *		post := it.Value()
+ *	}
+ *	if err := it.Err(); err != nil {
+ *	}
+ **/
+type Iterator[T any] struct {
+	// 按游标获取一页，next 为空表示没有更多
+	fetch func(ctx context.Context, cursor string) (items []T, next string, err error)
+
+	page    []T
+	cursor  string
+	done    bool
+	current T
+	err     error
+}
+
+func newIterator[T any](fetch func(ctx context.Context, cursor string) ([]T, string, error)) *Iterator[T] {
+	return &Iterator[T]{fetch: fetch}
+}
+
+// 前进到下一项，没有更多或出错时返回 false
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	for len(it.page) == 0 {
+		if it.done || it.err != nil {
+			return false
+		}
+		var next string
+		it.page, next, it.err = it.fetch(ctx, it.cursor)
+		if it.err != nil {
+			return false
+		}
+		it.cursor = next
+		if next == "" {
+			it.done = true
+		}
+	}
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	return true
+}
+
+// 当前项，须在 Next 返回 true 后调用
+func (it *Iterator[T]) Value() T {
+	return it.current
+}
+
+// 获取过程中的错误，正常取完时为 nil
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+type List struct {
+	ListID      uint32 `json:"list_id"`
+	OwnerID     uint32 `json:"user_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type ListDetail struct {
+	List
+	MemberList []User `json:"member_list"`
+}
+
+type CreateListRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type listMemberRequest struct {
+	ListID   uint32 `json:"list_id"`
+	UserID   uint32 `json:"user_id"`
+	UserName string `json:"user_name"`
+	Email    string `json:"email"`
+}
+
+type listsResponse struct {
+	Lists []List `json:"list_list"`
+}
+
+func (c *Client) CreateList(ctx context.Context, req CreateListRequest) (List, error) {
+	var list List
+	err := c.post(ctx, "/list", req, &list)
+	return list, err
+}
+
+// 列表及其成员
+func (c *Client) List(ctx context.Context, listID uint32) (ListDetail, error) {
+	var list ListDetail
+	err := c.get(ctx, "/list", url.Values{"list_id": {strconv.FormatUint(uint64(listID), 10)}}, &list)
+	return list, err
+}
+
+// 用户创建的列表，未公开的列表只有本人可见
+func (c *Client) Lists(ctx context.Context, userID uint32) ([]List, error) {
+	var resp listsResponse
+	err := c.get(ctx, "/list/user", url.Values{"user_id": {strconv.FormatUint(uint64(userID), 10)}}, &resp)
+	return resp.Lists, err
+}
+
+func (c *Client) AddListMember(ctx context.Context, listID uint32, member UserQuery) (string, error) {
+	return c.status(ctx, "/list/add", newListMemberRequest(listID, member))
+}
+
+func (c *Client) RemoveListMember(ctx context.Context, listID uint32, member UserQuery) (string, error) {
+	return c.status(ctx, "/list/remove", newListMemberRequest(listID, member))
+}
+
+func newListMemberRequest(listID uint32, member UserQuery) listMemberRequest {
+	return listMemberRequest{
+		ListID:   listID,
+		UserID:   member.ID,
+		UserName: member.UserName,
+		Email:    member.Email,
+	}
+}
+
+// 按时间由新到旧逐页获取列表成员的帖子；limit 为 0 时为 20
+func (c *Client) ListTimeline(listID uint32, limit int) *Iterator[Post] {
+	query := url.Values{"list_id": {strconv.FormatUint(uint64(listID), 10)}}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return c.timeline("/list/timeline", query)
+}
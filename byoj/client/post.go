@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+)
+
+type Post struct {
+	AuthorID    uint32 `json:"user_id"`
+	AuthorName  string `json:"user_name"`
+	AuthorEmail string `json:"email"`
+	PostID      uint32 `json:"post_id"`
+	Time        int64  `json:"time"`
+	// 原文；Text、Entities 及 HTML 为渲染后的结果
+	Content    string   `json:"content"`
+	Text       string   `json:"text"`
+	Entities   []Entity `json:"entities"`
+	HTML       string   `json:"html"`
+	IsPublic   bool     `json:"is_public"`
+	Sensitive  bool     `json:"sensitive"`
+	Collapsed  bool     `json:"collapsed"`
+	Bookmarked bool     `json:"bookmarked"`
+	Liked      bool     `json:"liked"`
+	LikeCount  int64    `json:"like_count"`
+	MediaList  []Media  `json:"media_list"`
+	Poll       *Poll    `json:"poll,omitempty"`
+	Card       *Card    `json:"card,omitempty"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+// 正文中的提及、话题及链接，Start 与 End 为 Text 中的字符位置
+type Entity struct {
+	Type  string `json:"type"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	URL   string `json:"url,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type Media struct {
+	MediaID     uint32 `json:"media_id"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	AltText     string `json:"alt_text"`
+	Blurhash    string `json:"blurhash"`
+}
+
+// 链接预览
+type Card struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
+}
+
+// 投票结束或已投票前，TotalVotes 及各项的 Votes 为 nil
+type Poll struct {
+	PollID        uint32       `json:"poll_id"`
+	EndTime       int64        `json:"end_time"`
+	Ended         bool         `json:"ended"`
+	Voted         bool         `json:"voted"`
+	VotedOptionID uint32       `json:"voted_option_id,omitempty"`
+	TotalVotes    *int64       `json:"total_votes,omitempty"`
+	Options       []PollOption `json:"options"`
+}
+
+type PollOption struct {
+	OptionID uint32 `json:"option_id"`
+	Text     string `json:"text"`
+	Votes    *int64 `json:"votes,omitempty"`
+}
+
+type CreatePostRequest struct {
+	AuthorID    uint32       `json:"user_id"`
+	AuthorName  string       `json:"user_name"`
+	AuthorEmail string       `json:"email"`
+	Content     string       `json:"content"`
+	MediaIDs    []uint32     `json:"media_ids"`
+	Poll        *PollRequest `json:"poll"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+// 2 到 4 个选项，EndTime 为 Unix 时间戳
+type PollRequest struct {
+	Options []string `json:"options"`
+	EndTime int64    `json:"end_time"`
+}
+
+// Held 为 true 时帖子命中过滤规则，审核通过前仅作者可见
+type PostCreated struct {
+	Status   string `json:"status"`
+	PostID   uint32 `json:"post_id"`
+	IsPublic bool   `json:"is_public"`
+	Held     bool   `json:"held"`
+}
+
+// 点赞、收藏等以帖子为目标的请求
+type postRequest struct {
+	PostID uint32 `json:"post_id"`
+}
+
+type voteRequest struct {
+	PostID   uint32 `json:"post_id"`
+	OptionID uint32 `json:"option_id"`
+}
+
+type postsResponse struct {
+	PostList   []Post `json:"post_list"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// 帖子列表的筛选条件
+type PostsQuery struct {
+	// 发布者，均为空时不限制
+	Author UserQuery
+	// 每页数量，为 0 时为 20，最大 100
+	Limit int
+	// "time" 或 "random"，为空时为 random；Timeline 固定按时间
+	OrderBy string
+	// 只返回该时间及之前的帖子，Unix 时间戳，为 0 时不限制
+	StartTime int64
+}
+
+func (q PostsQuery) values() url.Values {
+	query := q.Author.values()
+	if q.Limit > 0 {
+		query.Set("limit", strconv.Itoa(q.Limit))
+	}
+	if q.OrderBy != "" {
+		query.Set("order_by", q.OrderBy)
+	}
+	if q.StartTime != 0 {
+		query.Set("start_time", strconv.FormatInt(q.StartTime, 10))
+	}
+	return query
+}
+
+// 发布帖子，AuthorID 等须为当前登录的用户
+func (c *Client) CreatePost(ctx context.Context, req CreatePostRequest) (PostCreated, error) {
+	var created PostCreated
+	err := c.post(ctx, "/post", req, &created)
+	return created, err
+}
+
+// 获取一页帖子
+func (c *Client) Posts(ctx context.Context, q PostsQuery) ([]Post, error) {
+	var resp postsResponse
+	err := c.get(ctx, "/post", q.values(), &resp)
+	return resp.PostList, err
+}
+
+// 按时间由新到旧逐页获取帖子，忽略 q.OrderBy
+func (c *Client) Timeline(q PostsQuery) *Iterator[Post] {
+	query := q.values()
+	query.Set("order_by", "time")
+	return c.timeline("/post", query)
+}
+
+// 按时间逐页获取帖子的列表接口，屏蔽词过滤后可能不足一页，以 next_cursor 判断是否还有更多
+func (c *Client) timeline(path string, query url.Values) *Iterator[Post] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Post, string, error) {
+		page := url.Values{}
+		for k, v := range query {
+			page[k] = v
+		}
+		if cursor != "" {
+			page.Set("cursor", cursor)
+		}
+		var resp postsResponse
+		err := c.get(ctx, path, page, &resp)
+		return resp.PostList, resp.NextCursor, err
+	})
+}
+
+func (c *Client) Like(ctx context.Context, postID uint32) (string, error) {
+	return c.status(ctx, "/post/like", postRequest{PostID: postID})
+}
+
+func (c *Client) Unlike(ctx context.Context, postID uint32) (string, error) {
+	return c.status(ctx, "/post/unlike", postRequest{PostID: postID})
+}
+
+func (c *Client) Bookmark(ctx context.Context, postID uint32) (string, error) {
+	return c.status(ctx, "/post/bookmark", postRequest{PostID: postID})
+}
+
+func (c *Client) Unbookmark(ctx context.Context, postID uint32) (string, error) {
+	return c.status(ctx, "/post/unbookmark", postRequest{PostID: postID})
+}
+
+// 逐页获取收藏的帖子，由新到旧；limit 为 0 时使用服务端默认值
+func (c *Client) Bookmarks(limit int) *Iterator[Post] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Post, string, error) {
+		var resp postsResponse
+		err := c.get(ctx, "/post/bookmarks", pageQuery(cursor, limit), &resp)
+		return resp.PostList, resp.NextCursor, err
+	})
+}
+
+// 在投票中选择一项，返回投票后的结果
+func (c *Client) Vote(ctx context.Context, postID uint32, optionID uint32) (Poll, error) {
+	var poll Poll
+	err := c.post(ctx, "/post/vote", voteRequest{PostID: postID, OptionID: optionID}, &poll)
+	return poll, err
+}
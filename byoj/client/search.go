@@ -0,0 +1,61 @@
+package client
+
+import (
+	"context"
+	"net/url"
+)
+
+type Trending struct {
+	Window      string    `json:"window"`
+	HashtagList []Hashtag `json:"hashtag_list"`
+	PostList    []Post    `json:"post_list"`
+	UpdatedAt   int64     `json:"updated_at"`
+}
+
+type Hashtag struct {
+	Tag   string  `json:"tag"`
+	Count int64   `json:"count"`
+	Score float64 `json:"score"`
+}
+
+type searchResponse struct {
+	PostList   []Post `json:"post_list"`
+	UserList   []User `json:"user_list"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// 逐页搜索帖子，支持 "短语"、from:user_name 及 #hashtag；limit 为 0 时使用服务端默认值
+func (c *Client) SearchPosts(q string, limit int) *Iterator[Post] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Post, string, error) {
+		resp, err := c.search(ctx, q, "post", cursor, limit)
+		return resp.PostList, resp.NextCursor, err
+	})
+}
+
+// 逐页搜索用户；limit 为 0 时使用服务端默认值
+func (c *Client) SearchUsers(q string, limit int) *Iterator[User] {
+	return newIterator(func(ctx context.Context, cursor string) ([]User, string, error) {
+		resp, err := c.search(ctx, q, "user", cursor, limit)
+		return resp.UserList, resp.NextCursor, err
+	})
+}
+
+func (c *Client) search(ctx context.Context, q string, searchType string, cursor string, limit int) (searchResponse, error) {
+	query := pageQuery(cursor, limit)
+	query.Set("q", q)
+	query.Set("type", searchType)
+	var resp searchResponse
+	err := c.get(ctx, "/search", query, &resp)
+	return resp, err
+}
+
+// 热门话题及帖子，window 为 "1h" 或 "24h"，为空时为 24h
+func (c *Client) Trending(ctx context.Context, window string) (Trending, error) {
+	query := url.Values{}
+	if window != "" {
+		query.Set("window", window)
+	}
+	var trending Trending
+	err := c.get(ctx, "/trending", query, &trending)
+	return trending, err
+}
@@ -0,0 +1,192 @@
+package client
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+)
+
+type User struct {
+	ID        uint32 `json:"user_id"`
+	UserName  string `json:"user_name"`
+	Email     string `json:"email"`
+	RealName  string `json:"real_name"`
+	Bio       string `json:"bio"`
+	Verified  bool   `json:"verified"`
+	Deleted   bool   `json:"deleted"`
+	Suspended bool   `json:"suspended"`
+
+	// 各尺寸头像及横幅的地址，键为尺寸名称
+	AvatarURLs map[string]string `json:"avatar_urls"`
+	BannerURLs map[string]string `json:"banner_urls"`
+}
+
+type RegisterRequest struct {
+	UserName    string `json:"user_name"`
+	Email       string `json:"email"`
+	PasswordMD5 string `json:"password"`
+	RealName    string `json:"real_name"`
+	Bio         string `json:"bio"`
+}
+
+type Notification struct {
+	NotificationID uint32 `json:"notification_id"`
+	// 如 "post_approved"、"post_rejected"
+	Type    string `json:"type"`
+	PostID  uint32 `json:"post_id"`
+	Content string `json:"content"`
+	Read    bool   `json:"read"`
+	Time    int64  `json:"time"`
+}
+
+type profileRequest struct {
+	RealName string `json:"real_name"`
+	Bio      string `json:"bio"`
+}
+
+type notificationReadRequest struct {
+	UntilID uint32 `json:"notification_id"`
+}
+
+type usersResponse struct {
+	UserList []User `json:"user_list"`
+}
+
+type notificationsResponse struct {
+	NotificationList []Notification `json:"notification_list"`
+	NextCursor       string         `json:"next_cursor"`
+}
+
+// 用户 ID、用户名、邮箱三者任选其一，用于查询用户及指定关注等操作的目标
+type UserQuery struct {
+	ID       uint32 `json:"user_id,omitempty"`
+	UserName string `json:"user_name,omitempty"`
+	Email    string `json:"email,omitempty"`
+}
+
+func (q UserQuery) values() url.Values {
+	query := url.Values{}
+	if q.ID != 0 {
+		query.Set("user_id", strconv.FormatUint(uint64(q.ID), 10))
+	}
+	if q.UserName != "" {
+		query.Set("user_name", q.UserName)
+	}
+	if q.Email != "" {
+		query.Set("email", q.Email)
+	}
+	return query
+}
+
+// 登录用户与 UserQuery 相同，三者任选其一
+type LoginRequest struct {
+	UserQuery
+	// 服务端保存密码的 MD5，可用 HashPassword 计算
+	Password string `json:"password"`
+}
+
+// 密码的 MD5，与注册及登录接口约定的格式一致
+func HashPassword(password string) string {
+	sum := md5.Sum([]byte(password))
+	return hex.EncodeToString(sum[:])
+}
+
+// 注册用户，Password 同样为密码的 MD5
+func (c *Client) Register(ctx context.Context, req RegisterRequest) (string, error) {
+	return c.status(ctx, "/user/register", req)
+}
+
+// 登录并保存令牌，之后的请求自动携带
+func (c *Client) Login(ctx context.Context, req LoginRequest) (Tokens, error) {
+	var tokens Tokens
+	err := c.post(ctx, "/user/login", req, &tokens)
+	if err != nil {
+		return tokens, err
+	}
+	c.SetTokens(&tokens)
+	return tokens, nil
+}
+
+// 立即刷新令牌，通常无需调用，请求时会自动刷新
+func (c *Client) Refresh(ctx context.Context) (Tokens, error) {
+	current := c.Tokens()
+	if current == nil {
+		return Tokens{}, errNoRefreshToken
+	}
+	if _, err := c.refresh(ctx, current.AccessToken); err != nil {
+		return Tokens{}, err
+	}
+	return *c.Tokens(), nil
+}
+
+// 检查令牌是否有效
+func (c *Client) IsAuth(ctx context.Context) error {
+	return c.get(ctx, "/user/isauth", nil, nil)
+}
+
+func (c *Client) User(ctx context.Context, q UserQuery) (User, error) {
+	var user User
+	err := c.get(ctx, "/user", q.values(), &user)
+	return user, err
+}
+
+func (c *Client) Follow(ctx context.Context, q UserQuery) (string, error) {
+	return c.status(ctx, "/user/follow", q)
+}
+
+func (c *Client) Unfollow(ctx context.Context, q UserQuery) (string, error) {
+	return c.status(ctx, "/user/unfollow", q)
+}
+
+func (c *Client) Block(ctx context.Context, q UserQuery) (string, error) {
+	return c.status(ctx, "/user/block", q)
+}
+
+func (c *Client) Unblock(ctx context.Context, q UserQuery) (string, error) {
+	return c.status(ctx, "/user/unblock", q)
+}
+
+// 推荐关注的用户
+func (c *Client) Suggestions(ctx context.Context) ([]User, error) {
+	var resp usersResponse
+	err := c.get(ctx, "/user/suggestions", nil, &resp)
+	return resp.UserList, err
+}
+
+// 修改显示名称及简介，返回修改后的用户
+func (c *Client) UpdateProfile(ctx context.Context, realName string, bio string) (User, error) {
+	var user User
+	err := c.post(ctx, "/user/profile", profileRequest{RealName: realName, Bio: bio}, &user)
+	return user, err
+}
+
+/**
+ * 逐页获取通知，由新到旧
+ * @param: limit 每页数量，为 0 时使用服务端默认值
+ **/
+func (c *Client) Notifications(limit int) *Iterator[Notification] {
+	return newIterator(func(ctx context.Context, cursor string) ([]Notification, string, error) {
+		var resp notificationsResponse
+		err := c.get(ctx, "/user/notifications", pageQuery(cursor, limit), &resp)
+		return resp.NotificationList, resp.NextCursor, err
+	})
+}
+
+// 将 ID 不大于 untilID 的通知标记为已读
+func (c *Client) ReadNotifications(ctx context.Context, untilID uint32) (string, error) {
+	return c.status(ctx, "/user/notifications/read", notificationReadRequest{UntilID: untilID})
+}
+
+// 带游标的列表接口共用的查询参数
+func pageQuery(cursor string, limit int) url.Values {
+	query := url.Values{}
+	if cursor != "" {
+		query.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"byoj/client"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var errNotLoggedIn = errors.New("not logged in, run \"byitter login\" first")
+
+// 参数错误，flag 已输出错误信息及用法
+var errUsage = errors.New("usage")
+
+// 子命令的参数解析，错误信息写入 stderr
+func (c *cli) flagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet("byitter "+name, flag.ContinueOnError)
+	flags.SetOutput(c.stderr)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	return nil
+}
+
+/**
+ * 登录并将服务地址及令牌写入配置文件
+ * 密码依次取 -p、BYITTER_PASSWORD，均为空时从标准输入读取
+ **/
+func loginCommand(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("login")
+	userName := flags.String("u", "", "user name or email")
+	// 环境变量不作为默认值，以免 -h 及用法错误时输出密码
+	password := flags.String("p", "", "password, defaults to $BYITTER_PASSWORD or a prompt")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *userName == "" {
+		return errors.New("login: -u is required")
+	}
+	if *password == "" {
+		*password = os.Getenv("BYITTER_PASSWORD")
+	}
+	if *password == "" {
+		var err error
+		*password, err = c.readPassword()
+		if err != nil {
+			return err
+		}
+	}
+
+	req := client.LoginRequest{UserQuery: parseUser(*userName), Password: client.HashPassword(*password)}
+	tokens, err := c.client.Login(ctx, req)
+	if err != nil {
+		return err
+	}
+	c.config.Tokens = &tokens
+	if err := saveConfig(c.configPath, c.config); err != nil {
+		return err
+	}
+
+	// 不输出令牌
+	if c.output == "json" {
+		return c.printJSON(client.UserQuery{ID: tokens.ID, UserName: tokens.UserName})
+	}
+	fmt.Fprintf(c.stdout, "Logged in as %s (%d) on %s\n", tokens.UserName, tokens.ID, c.config.Server)
+	return nil
+}
+
+// 标准输入为终端时不回显，否则读取一行
+func (c *cli) readPassword() (string, error) {
+	if f, ok := c.stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(c.stderr, "Password: ")
+		buf, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(c.stderr)
+		return string(buf), err
+	}
+	line, err := bufio.NewReader(c.stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// 以当前登录的用户发布帖子，内容为 "-" 时从标准输入读取
+func postCommand(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("post")
+	warning := flags.String("cw", "", "content warning")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if c.config.Tokens == nil {
+		return errNotLoggedIn
+	}
+
+	text := strings.Join(flags.Args(), " ")
+	if text == "-" {
+		buf, err := io.ReadAll(c.stdin)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimRight(string(buf), "\n")
+	}
+	if text == "" {
+		return errors.New("post: content is required")
+	}
+
+	created, err := c.client.CreatePost(ctx, client.CreatePostRequest{
+		AuthorID:       c.config.Tokens.ID,
+		Content:        text,
+		ContentWarning: *warning,
+	})
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(created)
+	}
+	if created.Held {
+		fmt.Fprintf(c.stdout, "Post %d is held for review\n", created.PostID)
+		return nil
+	}
+	fmt.Fprintf(c.stdout, "Posted %d\n", created.PostID)
+	return nil
+}
+
+// 按时间由新到旧列出帖子，可限定发布者
+func timelineCommand(ctx context.Context, c *cli, args []string) error {
+	flags := c.flagSet("timeline")
+	userName := flags.String("u", "", "only posts of this user")
+	count := flags.Int("n", 20, "number of posts")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *count <= 0 {
+		return errors.New("timeline: -n must be positive")
+	}
+
+	limit := *count
+	if limit > 100 {
+		limit = 100
+	}
+	it := c.client.Timeline(client.PostsQuery{Author: client.UserQuery{UserName: *userName}, Limit: limit})
+	posts := make([]client.Post, 0, *count)
+	for len(posts) < *count && it.Next(ctx) {
+		posts = append(posts, it.Value())
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return c.printPosts(posts)
+}
+
+// 目前只有 user show
+func userCommand(ctx context.Context, c *cli, args []string) error {
+	if len(args) == 0 || args[0] != "show" {
+		return errors.New("user: usage: byitter user show <user_name> | -id <user_id>")
+	}
+	flags := c.flagSet("user show")
+	id := flags.Uint("id", 0, "user ID")
+	if err := parseFlags(flags, args[1:]); err != nil {
+		return err
+	}
+	q := client.UserQuery{ID: uint32(*id)}
+	if q.ID == 0 {
+		if flags.NArg() != 1 {
+			return errors.New("user show: a user name or -id is required")
+		}
+		q = parseUser(flags.Arg(0))
+	}
+
+	user, err := c.client.User(ctx, q)
+	if err != nil {
+		return err
+	}
+	return c.printUser(user)
+}
+
+func followCommand(ctx context.Context, c *cli, args []string) error {
+	return c.relation(ctx, "follow", args, c.client.Follow)
+}
+
+func unfollowCommand(ctx context.Context, c *cli, args []string) error {
+	return c.relation(ctx, "unfollow", args, c.client.Unfollow)
+}
+
+// 关注、取消关注共用，参数为一个用户名或邮箱
+func (c *cli) relation(ctx context.Context, name string, args []string, do func(context.Context, client.UserQuery) (string, error)) error {
+	if len(args) != 1 {
+		return fmt.Errorf("%s: usage: byitter %s <user_name>", name, name)
+	}
+	if c.config.Tokens == nil {
+		return errNotLoggedIn
+	}
+	status, err := do(ctx, parseUser(args[0]))
+	if err != nil {
+		return err
+	}
+	if c.output == "json" {
+		return c.printJSON(map[string]string{"status": status})
+	}
+	fmt.Fprintln(c.stdout, status)
+	return nil
+}
+
+// 含 @ 的视为邮箱，开头的 @ 视为用户名前缀
+func parseUser(s string) client.UserQuery {
+	if strings.HasPrefix(s, "@") {
+		return client.UserQuery{UserName: s[1:]}
+	}
+	if strings.Contains(s, "@") {
+		return client.UserQuery{Email: s}
+	}
+	return client.UserQuery{UserName: s}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"byoj/client"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://127.0.0.1:3435"
+
+// 配置文件，登录后保存服务地址及令牌
+type config struct {
+	Server string         `json:"server"`
+	Tokens *client.Tokens `json:"tokens,omitempty"`
+}
+
+// $BYITTER_CONFIG，未设置时为用户配置目录下的 byitter/config.json
+func defaultConfigPath() string {
+	if path := os.Getenv("BYITTER_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "byitter.json"
+	}
+	return filepath.Join(dir, "byitter", "config.json")
+}
+
+// 配置文件不存在时返回空配置
+func loadConfig(path string) (config, error) {
+	var cfg config
+	buf, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(buf, &cfg)
+	return cfg, err
+}
+
+// 配置文件中有令牌，只允许当前用户读写
+func saveConfig(path string, cfg config) error {
+	buf, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// 先写入临时文件再替换，避免写入中断时损坏原有配置
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
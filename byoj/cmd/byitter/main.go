@@ -0,0 +1,121 @@
+// byitter 是 Byitter 的命令行客户端，基于 byoj/client
+package main
+
+import (
+	"byoj/client"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const usage = `Usage: byitter [flags] <command> [args]
+
+Commands:
+  login -u <user_name> [-p <password>]   log in and save the tokens
+  post [-cw <warning>] <content>         publish a post, "-" reads the content from stdin
+  timeline [-u <user_name>] [-n <count>] list posts, newest first
+  user show <user_name> | -id <user_id>  show a user
+  follow <user_name>                     follow a user
+  unfollow <user_name>                   unfollow a user
+
+Flags:
+`
+
+// 命令行的运行环境，测试中替换输入输出
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configPath string
+	config     config
+	output     string
+	client     *client.Client
+}
+
+type command func(ctx context.Context, cli *cli, args []string) error
+
+var commands = map[string]command{
+	"login":    loginCommand,
+	"post":     postCommand,
+	"timeline": timelineCommand,
+	"user":     userCommand,
+	"follow":   followCommand,
+	"unfollow": unfollowCommand,
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+/**
+ * 解析全局参数并执行命令，返回进程退出码
+ * 服务地址依次取 -server、BYITTER_SERVER 及配置文件中登录时的地址
+ **/
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	c := &cli{stdin: stdin, stdout: stdout, stderr: stderr}
+
+	flags := flag.NewFlagSet("byitter", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	server := flags.String("server", os.Getenv("BYITTER_SERVER"), "server address, defaults to $BYITTER_SERVER or the one used at login")
+	flags.StringVar(&c.configPath, "config", defaultConfigPath(), "config file that stores the server and tokens")
+	flags.StringVar(&c.output, "o", "table", "output format: table or json")
+	lang := flags.String("lang", "", "language of server messages, such as en or zh")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if c.output != "table" && c.output != "json" {
+		fmt.Fprintf(stderr, "byitter: unknown output format %q\n", c.output)
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	cmd, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(stderr, "byitter: unknown command %q\n", flags.Arg(0))
+		flags.Usage()
+		return 2
+	}
+
+	var err error
+	c.config, err = loadConfig(c.configPath)
+	if err != nil {
+		fmt.Fprintln(stderr, "byitter:", err)
+		return 1
+	}
+	if *server != "" {
+		c.config.Server = *server
+	}
+	if c.config.Server == "" {
+		c.config.Server = defaultServer
+	}
+	c.client = client.New(c.config.Server, nil)
+	c.client.Language = *lang
+	c.client.SetTokens(c.config.Tokens)
+	c.client.OnTokenRefresh = func(tokens client.Tokens) {
+		c.config.Tokens = &tokens
+		if err := saveConfig(c.configPath, c.config); err != nil {
+			fmt.Fprintln(stderr, "byitter: save refreshed tokens:", err)
+		}
+	}
+
+	err = cmd(context.Background(), c, flags.Args()[1:])
+	if err == errUsage {
+		return 2
+	}
+	if err != nil {
+		// client.Error 已带有 byitter: 前缀
+		fmt.Fprintln(stderr, "byitter:", strings.TrimPrefix(err.Error(), "byitter: "))
+		return 1
+	}
+	return 0
+}
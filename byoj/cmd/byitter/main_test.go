@@ -0,0 +1,182 @@
+package main
+
+import (
+	"byoj/client"
+	"byoj/controllers"
+	"byoj/router"
+	"bytes"
+	"encoding/json"
+	"go/build"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+/**
+ * 启动连接真实路由的测试服务，需要数据库的接口在路由之前模拟
+ * 返回服务地址及每个请求的 Authorization
+ **/
+func newServer(t *testing.T) (string, map[string]string) {
+	e := echo.New()
+	router.Load(e)
+	authorizations := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorizations[r.Method+" "+r.URL.Path] = r.Header.Get("Authorization")
+		switch r.Method + " " + r.URL.Path {
+		case "POST /v1/user/login":
+			var req client.LoginRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.UserName != "alice" || req.Password != client.HashPassword("secret") {
+				e.ServeHTTP(w, r)
+				return
+			}
+			writeData(w, client.Tokens{
+				ID:                   7,
+				UserName:             "alice",
+				AccessToken:          "access",
+				AccessTokenExpireAt:  time.Now().Add(time.Hour).Unix(),
+				RefreshToken:         "refresh",
+				RefreshTokenExpireAt: time.Now().Add(time.Hour).Unix(),
+			})
+		case "POST /v1/post":
+			var req client.CreatePostRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.AuthorID != 7 || req.Content != "hello\nworld" {
+				t.Errorf("CreatePost request = %+v", req)
+			}
+			writeData(w, client.PostCreated{Status: "Success", PostID: 42, IsPublic: true})
+		case "GET /v1/post":
+			writeData(w, controllers.PostGetResponse{PostList: []controllers.PostResponse{
+				{PostID: 2, AuthorName: "alice", Time: 200, Content: "second\npost"},
+				{PostID: 1, AuthorName: "bob", Time: 100, Content: "first", ContentWarning: "spoilers"},
+			}})
+		default:
+			e.ServeHTTP(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, authorizations
+}
+
+func writeData(w http.ResponseWriter, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(controllers.ResponseStruct{Code: http.StatusOK, Message: "OK", Data: data})
+}
+
+// 执行命令，返回退出码及输出
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestCLI(t *testing.T) {
+	server, authorizations := newServer(t)
+	config := filepath.Join(t.TempDir(), "byitter", "config.json")
+	t.Setenv("BYITTER_PASSWORD", "")
+
+	if code, _, stderr := runCLI(t, "", "-config", config, "-server", server, "post", "hi"); code != 1 || !strings.Contains(stderr, "not logged in") {
+		t.Errorf("post before login: code %d, stderr %q", code, stderr)
+	}
+
+	// 密码从标准输入读取，登录后保存服务地址
+	code, stdout, stderr := runCLI(t, "secret\n", "-config", config, "-server", server, "login", "-u", "alice")
+	if code != 0 || !strings.Contains(stdout, "Logged in as alice (7)") {
+		t.Fatalf("login: code %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+	info, err := os.Stat(config)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("config file: %v, %v", info, err)
+	}
+	saved, _ := loadConfig(config)
+	if saved.Server != server || saved.Tokens == nil || saved.Tokens.AccessToken != "access" {
+		t.Errorf("saved config = %+v", saved)
+	}
+
+	code, stdout, _ = runCLI(t, "hello\nworld\n", "-config", config, "-o", "json", "post", "-")
+	var created client.PostCreated
+	if code != 0 || json.Unmarshal([]byte(stdout), &created) != nil || created.PostID != 42 {
+		t.Errorf("post: code %d, stdout %q", code, stdout)
+	}
+	if authorizations["POST /v1/post"] != "Bearer access" {
+		t.Errorf("post Authorization = %q", authorizations["POST /v1/post"])
+	}
+
+	code, stdout, _ = runCLI(t, "", "-config", config, "timeline", "-n", "5")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if code != 0 || len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "second post") || !strings.Contains(lines[2], "[CW: spoilers]") {
+		t.Errorf("timeline: code %d, stdout %q", code, stdout)
+	}
+
+	code, _, stderr = runCLI(t, "", "-config", config, "user", "show")
+	if code != 1 || !strings.Contains(stderr, "a user name or -id is required") {
+		t.Errorf("user show without a user: code %d, stderr %q", code, stderr)
+	}
+	// 服务端返回的错误只带一个 byitter: 前缀
+	code, _, stderr = runCLI(t, "wrong\n", "-config", config, "-lang", "zh", "login", "-u", "bob@example.com")
+	if code != 1 || !strings.HasPrefix(stderr, "byitter: ") || strings.Count(stderr, "byitter:") != 1 {
+		t.Errorf("failed login: code %d, stderr %q", code, stderr)
+	}
+
+	if code, _, _ := runCLI(t, "", "-config", config, "-o", "yaml", "timeline"); code != 2 {
+		t.Errorf("unknown output format: code %d, want 2", code)
+	}
+	if code, _, _ := runCLI(t, "", "-config", config, "timeline", "-x"); code != 2 {
+		t.Errorf("unknown flag: code %d, want 2", code)
+	}
+
+	// 用法中不输出环境变量中的密码
+	t.Setenv("BYITTER_PASSWORD", "hunter2-secret")
+	if code, _, stderr := runCLI(t, "", "-config", config, "login", "-h"); code != 2 || strings.Contains(stderr, "hunter2-secret") {
+		t.Errorf("login -h: code %d, stderr %q", code, stderr)
+	}
+	t.Setenv("BYITTER_PASSWORD", "secret")
+	if code, _, stderr := runCLI(t, "", "-config", config, "login", "-u", "alice"); code != 0 {
+		t.Errorf("login with $BYITTER_PASSWORD: code %d, stderr %q", code, stderr)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	cases := []struct{ in, want string }{
+		{"short", "short"},
+		{"a\n  b", "a b"},
+		{"你好，世界！", "你好，世…"},
+	}
+	for _, tc := range cases {
+		if got := truncate(tc.in, 5); got != tc.want {
+			t.Errorf("truncate(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// 命令行客户端只依赖 client，不引入服务端的包及其依赖
+func TestDependencies(t *testing.T) {
+	seen := make(map[string]bool)
+	var walk func(path string, dir string)
+	walk = func(path string, dir string) {
+		if seen[path] {
+			return
+		}
+		seen[path] = true
+		pkg, err := build.Import(path, dir, 0)
+		if err != nil {
+			t.Fatalf("import %s: %v", path, err)
+		}
+		if pkg.Goroot {
+			return
+		}
+		if strings.HasPrefix(path, "byoj/") && path != "byoj/cmd/byitter" && path != "byoj/client" {
+			t.Errorf("byitter depends on %s", path)
+		}
+		for _, imported := range pkg.Imports {
+			walk(imported, pkg.Dir)
+		}
+	}
+	walk("byoj/cmd/byitter", ".")
+}
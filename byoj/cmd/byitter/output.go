@@ -0,0 +1,62 @@
+package main
+
+import (
+	"byoj/client"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode/utf8"
+)
+
+// 表格中帖子内容的最大字符数
+const maxContentWidth = 60
+
+func (c *cli) printJSON(v interface{}) error {
+	enc := json.NewEncoder(c.stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (c *cli) printPosts(posts []client.Post) error {
+	if c.output == "json" {
+		return c.printJSON(posts)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTIME\tAUTHOR\tCONTENT")
+	for _, post := range posts {
+		text := post.Content
+		if post.ContentWarning != "" {
+			text = "[CW: " + post.ContentWarning + "]"
+		}
+		fmt.Fprintf(w, "%d\t%s\t@%s\t%s\n", post.PostID, time.Unix(post.Time, 0).Format("2006-01-02 15:04"), post.AuthorName, truncate(text, maxContentWidth))
+	}
+	return w.Flush()
+}
+
+func (c *cli) printUser(user client.User) error {
+	if c.output == "json" {
+		return c.printJSON(user)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "ID\t%d\n", user.ID)
+	fmt.Fprintf(w, "USER NAME\t@%s\n", user.UserName)
+	fmt.Fprintf(w, "NAME\t%s\n", user.RealName)
+	fmt.Fprintf(w, "BIO\t%s\n", truncate(user.Bio, maxContentWidth))
+	fmt.Fprintf(w, "VERIFIED\t%t\n", user.Verified)
+	if user.Suspended {
+		fmt.Fprintln(w, "SUSPENDED\ttrue")
+	}
+	return w.Flush()
+}
+
+// 合并为一行并按字符截断，表格中不换行
+func truncate(s string, width int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
@@ -84,7 +84,12 @@ func generateToken(userID uint32, userName string, expireAt time.Time, secretKey
 }
 
 func GetClaimsFromHeader(c echo.Context) (claims Claims, err error) {
-	bearerToken := strings.Split(c.Request().Header.Get(tokenHeaderName), " ")
+	return ParseBearerToken(c.Request().Header.Get(tokenHeaderName))
+}
+
+// 解析 "Bearer <token>" 形式的 Authorization 请求头，gRPC 的 authorization metadata 同样适用
+func ParseBearerToken(header string) (claims Claims, err error) {
+	bearerToken := strings.Split(header, " ")
 	if len(bearerToken) < 2 {
 		return Claims{}, errors.New("invalid header")
 	}
@@ -92,10 +97,29 @@ func GetClaimsFromHeader(c echo.Context) (claims Claims, err error) {
 		return Claims{}, errors.New("invalid header")
 	}
 
-	tokenString := bearerToken[1]
+	return ParseAccessToken(bearerToken[1])
+}
+
+// 校验 access token 的签名并解析其中的声明，REST 的请求头与 gRPC 的 metadata 共用
+func ParseAccessToken(tokenString string) (claims Claims, err error) {
+	return parseToken(tokenString, GetJwtAccessSecretKey())
+}
+
+// 校验 refresh token 的签名及有效期并解析其中的声明
+func ParseRefreshToken(tokenString string) (claims Claims, err error) {
+	return parseToken(tokenString, GetJwtRefreshSecretKey())
+}
+
+// 令牌签名有效但已过期，用于返回 token_expired 而不是 unauthorized
+func IsTokenExpired(err error) bool {
+	var validationErr *jwt.ValidationError
+	return errors.As(err, &validationErr) && validationErr.Errors == jwt.ValidationErrorExpired
+}
+
+func parseToken(tokenString string, secretKey string) (claims Claims, err error) {
 	claims = Claims{}
 	_, err = jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
-		return []byte(GetJwtAccessSecretKey()), nil
+		return []byte(secretKey), nil
 	})
 	if err != nil {
 		return Claims{}, err
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/media"
+	"byoj/model"
+	"byoj/storage"
+	"byoj/utils/logs"
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type profileImageKind struct {
+	name   string
+	column string
+	prefix string
+	sizes  []media.ImageSize
+}
+
+var (
+	avatarKind = profileImageKind{name: "avatar", column: model.UserAvatarColumn, prefix: "avatar/", sizes: media.AvatarSizes}
+	bannerKind = profileImageKind{name: "banner", column: model.UserBannerColumn, prefix: "banner/", sizes: media.BannerSizes}
+)
+
+func (k profileImageKind) size(name string) (media.ImageSize, bool) {
+	if name == "" {
+		return k.sizes[len(k.sizes)-1], true
+	}
+	for _, size := range k.sizes {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return media.ImageSize{}, false
+}
+
+// "avatar/abcd.jpg" 对应 large 尺寸的文件为 "avatar/abcd-large.jpg"
+func sizedKey(key string, size string) string {
+	ext := path.Ext(key)
+	return strings.TrimSuffix(key, ext) + "-" + size + ext
+}
+
+// key 中的随机部分作为版本号，头像更新后地址随之改变，可以长期缓存
+func profileImageURLs(k profileImageKind, userID uint32, key string) map[string]string {
+	urls := make(map[string]string, len(k.sizes))
+	if key == "" && k.name != avatarKind.name {
+		return urls
+	}
+	base := "/v1/user/" + k.name + "/" + strconv.FormatUint(uint64(userID), 10) + "?size="
+	version := ""
+	if key != "" {
+		version = "&v=" + strings.TrimSuffix(path.Base(key), path.Ext(key))
+	}
+	for _, size := range k.sizes {
+		urls[size.Name] = base + size.Name + version
+	}
+	return urls
+}
+
+func UserAvatarPOST(c echo.Context) error {
+	logs.Debug("POST /user/avatar")
+	return uploadProfileImage(c, avatarKind)
+}
+
+func UserBannerPOST(c echo.Context) error {
+	logs.Debug("POST /user/banner")
+	return uploadProfileImage(c, bannerKind)
+}
+
+/**
+ * 上传头像或背景图，裁剪并缩放为各个尺寸后保存
+ * 可选表单字段 crop_x, crop_y, crop_width, crop_height 指定裁剪区域，默认居中裁剪
+ **/
+func uploadProfileImage(c echo.Context, k profileImageKind) error {
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, media.MaxImageSize()+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return ResponseError(c, ErrInvalidUpload, err)
+	}
+
+	var rect image.Rectangle
+	if c.FormValue("crop_width") != "" {
+		var v [4]int
+		for i, name := range []string{"crop_x", "crop_y", "crop_width", "crop_height"} {
+			v[i], err = strconv.Atoi(c.FormValue(name))
+			if err != nil || v[i] < 0 {
+				return ResponseInvalidParameter(c, name, "param.invalid")
+			}
+		}
+		rect = image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3])
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ResponseError(c, ErrInvalidUpload, err)
+	}
+	defer file.Close()
+
+	img, info, err := media.DecodeImage(file, fileHeader.Size)
+	if err != nil {
+		return responseMediaError(c, err)
+	}
+
+	key := ""
+	var stored []string
+	for _, size := range k.sizes {
+		resized := media.Resize(media.Crop(img, rect, size.Width, size.Height), size.Width, size.Height)
+		data, contentType, extension, err := media.Encode(resized, info.ContentType)
+		if err != nil {
+			deleteKeys(req.Context(), stored)
+			return ResponseInternalServerError(c, "Encode image failed.", err)
+		}
+		if key == "" {
+			key, err = randomKey(k.prefix, extension)
+			if err != nil {
+				return ResponseInternalServerError(c, "Generate image key failed.", err)
+			}
+		}
+		err = storage.GetStore().Put(req.Context(), sizedKey(key, size.Name), bytes.NewReader(data), int64(len(data)), contentType)
+		if err != nil {
+			deleteKeys(req.Context(), stored)
+			return ResponseInternalServerError(c, "Failed to store image file.", err)
+		}
+		stored = append(stored, sizedKey(key, size.Name))
+	}
+
+	oldKey, err := model.UpdateUserImage(claims.ID, k.column, key)
+	if err != nil {
+		deleteKeys(req.Context(), stored)
+		return ResponseInternalServerError(c, "Failed to update user's "+k.name+".", err)
+	}
+	if oldKey != "" {
+		var old []string
+		for _, size := range k.sizes {
+			old = append(old, sizedKey(oldKey, size.Name))
+		}
+		deleteKeys(req.Context(), old)
+	}
+
+	return ResponseOK(c, profileImageURLs(k, claims.ID, key))
+}
+
+func deleteKeys(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := storage.GetStore().Delete(ctx, key); err != nil {
+			logs.Warn("Delete image file failed.", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+func UserAvatarGET(c echo.Context) error {
+	logs.Debug("GET /user/avatar/:id")
+	return serveProfileImage(c, avatarKind)
+}
+
+func UserBannerGET(c echo.Context) error {
+	logs.Debug("GET /user/banner/:id")
+	return serveProfileImage(c, bannerKind)
+}
+
+// 未上传头像时返回根据用户名生成的默认头像
+func serveProfileImage(c echo.Context, k profileImageKind) error {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return ResponseInvalidParameter(c, "id", "param.invalid")
+	}
+	size, ok := k.size(c.QueryParam("size"))
+	if !ok {
+		return ResponseInvalidParameter(c, "size", "param.invalid")
+	}
+
+	user, err := model.FindUserByID(uint32(userID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return ResponseInternalServerError(c, "Find user failed.", err)
+	}
+
+	key := user.AvatarKey
+	if k.name == bannerKind.name {
+		key = user.BannerKey
+	}
+	header := c.Response().Header()
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if key == "" {
+		if k.name != avatarKind.name {
+			return c.NoContent(http.StatusNotFound)
+		}
+		var buf bytes.Buffer
+		err = png.Encode(&buf, media.Identicon(user.UserName, size.Width))
+		if err != nil {
+			return ResponseInternalServerError(c, "Generate default avatar failed.", err)
+		}
+		header.Set("Cache-Control", "public, max-age=86400")
+		return c.Blob(http.StatusOK, "image/png", buf.Bytes())
+	}
+
+	// 带有旧版本号的请求重定向到当前版本，避免缓存旧图片
+	urls := profileImageURLs(k, user.ID, key)
+	if v := c.QueryParam("v"); v != "" && !strings.HasSuffix(urls[size.Name], "&v="+v) {
+		return c.Redirect(http.StatusFound, urls[size.Name])
+	}
+
+	etag := `"` + sizedKey(key, size.Name) + `"`
+	header.Set("ETag", etag)
+	if c.QueryParam("v") != "" {
+		header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		header.Set("Cache-Control", "public, max-age=300")
+	}
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	r, err := storage.GetStore().Get(c.Request().Context(), sizedKey(key, size.Name))
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to read image file.", err)
+	}
+	defer r.Close()
+
+	contentType := "image/jpeg"
+	if path.Ext(key) == ".png" {
+		contentType = "image/png"
+	}
+	return c.Stream(http.StatusOK, contentType, r)
+}
@@ -0,0 +1,203 @@
+package controllers
+
+import (
+	"byoj/utils/logs"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+/**
+ * 解析请求参数到 obj 并按 validate 标签校验，失败时已写入 400 响应并返回 false
+ * 依次读取请求体、查询参数及路径参数，后读取的覆盖先读取的
+ * 查询参数只用于 GET、DELETE 等没有请求体语义的方法，其他方法的查询参数不会覆盖请求体
+ * 请求体为 JSON 或表单，GET 请求带有 JSON 请求体时同样读取，兼容旧客户端
+ * 查询参数的字段名取 query 标签，路径参数取 param 标签，表单取 form 标签，没有时均取 json 标签
+ * @param: obj 结构体指针
+ **/
+func Bind(c echo.Context, obj interface{}) (bool, error) {
+	form, err := bindBody(c, obj)
+	if err == errUnsupportedContentType {
+		return false, ResponseError(c, ErrUnsupportedMedia, err)
+	}
+	if err != nil {
+		logs.Warn("Failed to parse request data.", zap.Error(err))
+		return false, ResponseError(c, ErrInvalidRequest, err)
+	}
+
+	details := bindValues(obj, form, "form")
+	switch c.Request().Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete:
+		details = append(details, bindValues(obj, c.QueryParams(), "query")...)
+	}
+	path := make(map[string][]string)
+	for i, name := range c.ParamNames() {
+		path[name] = []string{c.ParamValues()[i]}
+	}
+	details = append(details, bindValues(obj, path, "param")...)
+	if len(details) > 0 {
+		return false, ResponseError(c, ErrInvalidParameter, nil, details...)
+	}
+
+	logs.Debug("Parsed struct:", zap.Any("obj", obj))
+	if details := validateRequest(obj); len(details) > 0 {
+		return false, ResponseError(c, ErrInvalidParameter, nil, details...)
+	}
+	return true, nil
+}
+
+var (
+	errUnsupportedContentType = errors.New("unsupported content type")
+	errUnsupportedField       = errors.New("unsupported field type")
+)
+
+// 解析 JSON 请求体，表单请求返回表单参数；没有请求体时不做处理
+func bindBody(c echo.Context, obj interface{}) (map[string][]string, error) {
+	req := c.Request()
+	if req.Body == nil || req.ContentLength == 0 {
+		return nil, nil
+	}
+	ctype, _, _ := mime.ParseMediaType(req.Header.Get(echo.HeaderContentType))
+	switch ctype {
+	case echo.MIMEApplicationJSON:
+		err := json.NewDecoder(req.Body).Decode(obj)
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, err
+	case echo.MIMEApplicationForm, echo.MIMEMultipartForm:
+		return c.FormParams()
+	case "":
+		// GET 请求可能带有未声明类型的请求体，忽略即可
+		if req.Method == http.MethodGet || req.Method == http.MethodDelete {
+			return nil, nil
+		}
+	}
+	return nil, errUnsupportedContentType
+}
+
+/**
+ * 将字符串参数写入结构体中对应的字段，返回无法转换的字段
+ * 支持字符串、布尔、数字及其切片、指针，其他类型的字段忽略
+ **/
+func bindValues(obj interface{}, values map[string][]string, tag string) []FieldError {
+	if len(values) == 0 {
+		return nil
+	}
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var details []FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := fieldName(f, tag)
+		if name == "" {
+			continue
+		}
+		input, ok := values[name]
+		if !ok || len(input) == 0 {
+			continue
+		}
+		if err := setField(v.Field(i), input); err != nil && err != errUnsupportedField {
+			details = append(details, newFieldError(name, "type", "param.type"))
+		}
+	}
+	return details
+}
+
+func fieldName(f reflect.StructField, tag string) string {
+	name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+	if name == "" {
+		name = strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+	}
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func setField(field reflect.Value, input []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setField(elem.Elem(), input); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice:
+		// 同时支持 ?id=1&id=2 与 ?id=1,2，字符串切片不拆分
+		items := input
+		if field.Type().Elem().Kind() != reflect.String {
+			items = nil
+			for _, s := range input {
+				for _, item := range strings.Split(s, ",") {
+					items = append(items, strings.TrimSpace(item))
+				}
+			}
+		}
+		slice := reflect.MakeSlice(field.Type(), len(items), len(items))
+		for i, s := range items {
+			if err := setScalar(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		field.Set(slice)
+		return nil
+	}
+	return setScalar(field, input[0])
+}
+
+func setScalar(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return errUnsupportedField
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/logs"
+	"strconv"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+type BookmarkRequest struct {
+	PostID uint32 `json:"post_id" validate:"required"`
+}
+
+type BookmarkGetResponse struct {
+	PostList   []PostResponse `json:"post_list"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+func PostBookmarkPOST(c echo.Context) error {
+	logs.Debug("POST /post/bookmark")
+
+	bookmarkRequest := BookmarkRequest{}
+	_ok, err := Bind(c, &bookmarkRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	post, err := model.FindPostByPostID(bookmarkRequest.PostID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrPostNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Find post failed.", err)
+	}
+	if !post.IsPublic && post.AuthorID != claims.ID {
+		return ResponseError(c, ErrForbidden, nil)
+	}
+
+	err = model.CreateBookmark(claims.ID, post.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to create bookmark.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.bookmark"),
+	})
+}
+
+func PostUnbookmarkPOST(c echo.Context) error {
+	logs.Debug("POST /post/unbookmark")
+
+	bookmarkRequest := BookmarkRequest{}
+	_ok, err := Bind(c, &bookmarkRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	err = model.DeleteBookmark(claims.ID, bookmarkRequest.PostID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to delete bookmark.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.unbookmark"),
+	})
+}
+
+func PostBookmarksGET(c echo.Context) error {
+	logs.Debug("GET /post/bookmarks")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	var cursor uint64
+	if s := c.QueryParam("cursor"); s != "" {
+		cursor, err = strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return ResponseInvalidParameter(c, "cursor", "param.invalid")
+		}
+	}
+	limit := 20
+	if s := c.QueryParam("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > 100 {
+			return ResponseInvalidParameter(c, "limit", "param.invalid")
+		}
+	}
+
+	bookmarks, err := model.GetBookmarks(claims.ID, uint32(cursor), limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get bookmarks failed.", err)
+	}
+
+	postIDs := make([]uint32, 0, len(bookmarks))
+	for _, bookmark := range bookmarks {
+		postIDs = append(postIDs, bookmark.PostID)
+	}
+	posts, err := model.FindPostsByIDs(postIDs)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get bookmarked posts failed.", err)
+	}
+
+	resp := BookmarkGetResponse{
+		PostList: buildPostResponses(posts, nil, claims.ID),
+	}
+	if len(bookmarks) == limit {
+		resp.NextCursor = strconv.FormatUint(uint64(bookmarks[len(bookmarks)-1].ID), 10)
+	}
+	return ResponseOK(c, resp)
+}
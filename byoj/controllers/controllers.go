@@ -1,43 +1,82 @@
 package controllers
 
 import (
-	"byoj/utils/logs"
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/content"
+	"byoj/utils/i18n"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo"
-	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
-type ErrorMessage struct {
-	Message string `json:"msg"`
-	Err     string `json:"err"`
-}
-
 type StatusMessage struct {
 	Status string `json:"status"`
 }
 
+type SuspensionMessage struct {
+	Code      string `json:"code"`
+	Message   string `json:"msg"`
+	RequestID string `json:"request_id"`
+	Reason    string `json:"reason"`
+	Permanent bool   `json:"permanent"`
+	Until     int64  `json:"until"`
+}
+
 type ResponseStruct struct {
 	Code    int         `json:"code"`
 	Message string      `json:"msg"`
 	Data    interface{} `json:"data"`
 }
 
-func Bind(c echo.Context, obj interface{}) (bool, error) {
-	err := c.Bind(&obj)
+// 获取请求头中 token 对应的用户 ID，未登录或 token 无效时返回 0
+func GetViewerID(c echo.Context) uint32 {
+	claims, err := auth.GetClaimsFromHeader(c)
 	if err != nil {
-		logs.Warn("Failed to parse request data.", zap.Error(err))
-		return false, c.JSON(http.StatusBadRequest, ResponseStruct{
-			Code:    http.StatusBadRequest,
-			Message: "Bad Request",
-			Data: ErrorMessage{
-				Message: "Failed to parse request data.",
-				Err:     err.Error(),
-			},
-		})
+		return 0
+	}
+	return claims.ID
+}
+
+/**
+ * 校验 Authorization 请求头中的 access token，REST 与 gRPC 共用
+ * 令牌已过期时返回 token_expired，其他无效令牌返回 unauthorized
+ **/
+func VerifyToken(header string) (auth.Claims, error) {
+	claims, err := auth.ParseBearerToken(header)
+	if auth.IsTokenExpired(err) {
+		return auth.Claims{}, newRequestError(ErrTokenExpired, err)
+	}
+	if err != nil {
+		return auth.Claims{}, newRequestError(ErrUnauthorized, err)
+	}
+	if err := claims.Valid(); err != nil {
+		return auth.Claims{}, newRequestError(ErrUnauthorized, err)
+	}
+	return claims, nil
+}
+
+/**
+ * 查询令牌对应的用户，写入操作前调用
+ * 用户不存在或已改名时令牌不再可用，被封禁的用户返回 user_suspended
+ **/
+func Authenticate(claims auth.Claims) (model.User, error) {
+	user, err := model.FindUserByID(claims.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return user, newRequestError(ErrUnauthorized, err)
+		}
+		return user, internalRequestError("Find user by ID failed.", err)
+	}
+	if user.UserName != claims.UserName {
+		return user, newRequestError(ErrUnauthorized, nil)
 	}
-	logs.Debug("Parsed struct:", zap.Any("obj", obj))
-	return true, nil
+	if user.IsSuspended(time.Now()) {
+		return user, suspendedError(user)
+	}
+	return user, nil
 }
 
 func ResponseOK(c echo.Context, data interface{}) error {
@@ -48,62 +87,35 @@ func ResponseOK(c echo.Context, data interface{}) error {
 	})
 }
 
-func ResponseBadRequest(c echo.Context, errMessage string, err error) error {
-	Err := ""
-	if err != nil {
-		Err = err.Error()
+func ResponseSuspended(c echo.Context, user model.User) error {
+	until := int64(0)
+	if !user.SuspendPermanent {
+		until = user.SuspendUntil.Unix()
 	}
-	return c.JSON(http.StatusBadRequest, ResponseStruct{
-		Code:    http.StatusBadRequest,
-		Message: "Bad Request",
-		Data: ErrorMessage{
-			Message: errMessage,
-			Err:     Err,
+	return c.JSON(ErrUserSuspended.Status, ResponseStruct{
+		Code:    ErrUserSuspended.Status,
+		Message: http.StatusText(ErrUserSuspended.Status),
+		Data: SuspensionMessage{
+			Code:      ErrUserSuspended.Code,
+			Message:   ErrUserSuspended.Message(Lang(c)),
+			RequestID: RequestID(c),
+			Reason:    user.SuspendReason,
+			Permanent: user.SuspendPermanent,
+			Until:     until,
 		},
 	})
 }
 
-func ResponseInternalServerError(c echo.Context, errMessage string, err error) error {
-	Err := ""
-	if err != nil {
-		Err = err.Error()
-	}
-	return c.JSON(http.StatusInternalServerError, ResponseStruct{
-		Code:    http.StatusInternalServerError,
-		Message: "Internal Server Error",
-		Data: ErrorMessage{
-			Message: errMessage,
-			Err:     Err,
-		},
-	})
+func ResponseContentInvalid(c echo.Context, err *content.RuleError) error {
+	return ResponseRequestError(c, contentInvalidError(err))
 }
 
-func ResponseUnauthorized(c echo.Context, errMessage string, err error) error {
-	Err := ""
-	if err != nil {
-		Err = err.Error()
-	}
-	return c.JSON(http.StatusUnauthorized, ResponseStruct{
-		Code:    http.StatusUnauthorized,
-		Message: "Unauthorized",
-		Data: ErrorMessage{
-			Message: errMessage,
-			Err:     Err,
-		},
-	})
-}
-
-func ResponseForbidden(c echo.Context, errMessage string, err error) error {
-	Err := ""
-	if err != nil {
-		Err = err.Error()
-	}
-	return c.JSON(http.StatusForbidden, ResponseStruct{
-		Code:    http.StatusForbidden,
-		Message: "Forbidden",
-		Data: ErrorMessage{
-			Message: errMessage,
-			Err:     Err,
-		},
+func contentInvalidError(err *content.RuleError) error {
+	return newRequestError(ErrContentInvalid, nil, FieldError{
+		Field:  "content",
+		Rule:   err.Rule,
+		Limit:  err.Limit,
+		Actual: err.Actual,
+		msg:    i18n.NewError("content."+err.Rule, err.Limit),
 	})
 }
@@ -0,0 +1,280 @@
+package controllers
+
+import (
+	"byoj/model"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+/**
+ * 错误码目录中的一项
+ * Code 为稳定的字符串，客户端应据此判断错误类型；展示文本取自 i18n 目录中的 "error.<Code>"，可能调整
+ **/
+type APIError struct {
+	Status int
+	Code   string
+}
+
+func (e *APIError) Error() string {
+	return e.Code
+}
+
+// 按语言返回展示文本
+func (e *APIError) Message(lang string) string {
+	return i18n.Translate(lang, "error."+e.Code)
+}
+
+// 错误码目录，新增错误时在此补充并在每种语言的目录中添加文本，已发布的 Code 不可修改
+var (
+	ErrInvalidRequest     = &APIError{http.StatusBadRequest, "invalid_request"}
+	ErrInvalidParameter   = &APIError{http.StatusBadRequest, "invalid_parameter"}
+	ErrInvalidUpload      = &APIError{http.StatusBadRequest, "invalid_upload"}
+	ErrContentInvalid     = &APIError{http.StatusBadRequest, "content_invalid"}
+	ErrContentRejected    = &APIError{http.StatusBadRequest, "content_rejected"}
+	ErrSelfTarget         = &APIError{http.StatusBadRequest, "self_target"}
+	ErrTooManyMutedWords  = &APIError{http.StatusBadRequest, "too_many_muted_words"}
+	ErrTooManyListMembers = &APIError{http.StatusBadRequest, "too_many_list_members"}
+	ErrQueryTooDeep       = &APIError{http.StatusBadRequest, "query_too_deep"}
+	ErrQueryTooComplex    = &APIError{http.StatusBadRequest, "query_too_complex"}
+	ErrUnauthorized       = &APIError{http.StatusUnauthorized, "unauthorized"}
+	ErrTokenExpired       = &APIError{http.StatusUnauthorized, "token_expired"}
+	ErrWrongPassword      = &APIError{http.StatusUnauthorized, "wrong_password"}
+	ErrForbidden          = &APIError{http.StatusForbidden, "forbidden"}
+	ErrAdminRequired      = &APIError{http.StatusForbidden, "admin_required"}
+	ErrUserSuspended      = &APIError{http.StatusForbidden, "user_suspended"}
+	ErrUserNotVerified    = &APIError{http.StatusForbidden, "user_not_verified"}
+	ErrNotFound           = &APIError{http.StatusNotFound, "not_found"}
+	ErrUserNotFound       = &APIError{http.StatusNotFound, "user_not_found"}
+	ErrPostNotFound       = &APIError{http.StatusNotFound, "post_not_found"}
+	ErrPollNotFound       = &APIError{http.StatusNotFound, "poll_not_found"}
+	ErrListNotFound       = &APIError{http.StatusNotFound, "list_not_found"}
+	ErrMediaNotFound      = &APIError{http.StatusNotFound, "media_not_found"}
+	ErrScheduledNotFound  = &APIError{http.StatusNotFound, "scheduled_post_not_found"}
+	ErrHeldPostNotFound   = &APIError{http.StatusNotFound, "held_post_not_found"}
+	ErrFilterRuleNotFound = &APIError{http.StatusNotFound, "filter_rule_not_found"}
+	ErrMethodNotAllowed   = &APIError{http.StatusMethodNotAllowed, "method_not_allowed"}
+	ErrPollEnded          = &APIError{http.StatusConflict, "poll_ended"}
+	ErrAlreadyVoted       = &APIError{http.StatusConflict, "already_voted"}
+	ErrUserDeleted        = &APIError{http.StatusGone, "user_deleted"}
+	ErrPayloadTooLarge    = &APIError{http.StatusRequestEntityTooLarge, "payload_too_large"}
+	ErrUnsupportedMedia   = &APIError{http.StatusUnsupportedMediaType, "unsupported_media_type"}
+	ErrInternal           = &APIError{http.StatusInternalServerError, "internal_error"}
+	ErrServiceUnavailable = &APIError{http.StatusServiceUnavailable, "service_unavailable"}
+)
+
+// echo 返回的 HTTP 错误按状态码对应到目录中的错误
+var statusErrors = map[int]*APIError{
+	http.StatusBadRequest:            ErrInvalidRequest,
+	http.StatusUnauthorized:          ErrUnauthorized,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusMethodNotAllowed:      ErrMethodNotAllowed,
+	http.StatusRequestEntityTooLarge: ErrPayloadTooLarge,
+	http.StatusUnsupportedMediaType:  ErrUnsupportedMedia,
+	http.StatusServiceUnavailable:    ErrServiceUnavailable,
+}
+
+// 字段级错误，Rule 为违反的规则，Limit 与 Actual 为相关的限制及实际值
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"msg"`
+	Limit   int    `json:"limit,omitempty"`
+	Actual  int    `json:"actual,omitempty"`
+
+	// 待翻译的文本，返回响应时按请求的语言写入 Message
+	msg *i18n.Error
+}
+
+/**
+ * 构造字段级错误，文本在返回响应时翻译
+ * @param: key i18n 目录中的键，字段名作为第一个参数，args 依次排在其后
+ **/
+func newFieldError(field string, rule string, key string, args ...interface{}) FieldError {
+	return FieldError{Field: field, Rule: rule, msg: i18n.NewError(key, append([]interface{}{field}, args...)...)}
+}
+
+// 由检查函数返回的错误构造字段级错误，*i18n.Error 在返回响应时翻译，其他错误原样返回
+func fieldErrorOf(field string, err error) FieldError {
+	if msg, ok := err.(*i18n.Error); ok {
+		return FieldError{Field: field, msg: msg}
+	}
+	return FieldError{Field: field, Message: err.Error()}
+}
+
+/**
+ * REST、GraphQL 及 gRPC 共用的处理函数返回的错误，由各自的入口转换为响应
+ * 不同入口按请求的语言翻译文本，并附上各自的请求 ID
+ **/
+type RequestError struct {
+	API *APIError
+	// 导致该错误的原始错误，只写入日志，不返回给客户端
+	Cause   error
+	Details []FieldError
+	// 内部错误在日志中的描述
+	LogMessage string
+	// 用户被封禁时不为 nil，REST 接口据此返回封禁原因及期限
+	Suspended *model.User
+}
+
+func (e *RequestError) Error() string {
+	return e.API.Code
+}
+
+// 按语言写入字段级错误的文本并返回
+func (e *RequestError) LocalizedDetails(lang string) []FieldError {
+	translateDetails(lang, e.Details)
+	return e.Details
+}
+
+func newRequestError(apiErr *APIError, err error, details ...FieldError) error {
+	return &RequestError{API: apiErr, Cause: err, Details: details}
+}
+
+// internal_error，logMessage 与 ResponseInternalServerError 一样只写入日志
+func internalRequestError(logMessage string, err error) error {
+	return &RequestError{API: ErrInternal, Cause: err, LogMessage: logMessage}
+}
+
+// 检查函数返回错误时的 invalid_parameter
+func invalidFieldError(field string, err error) error {
+	return newRequestError(ErrInvalidParameter, err, fieldErrorOf(field, err))
+}
+
+// invalid_parameter，附带单个字段的错误，参数与 ResponseInvalidParameter 一致
+func invalidParameterError(field string, key string, args ...interface{}) error {
+	return newRequestError(ErrInvalidParameter, nil, newFieldError(field, "", key, args...))
+}
+
+func suspendedError(user model.User) error {
+	return &RequestError{API: ErrUserSuspended, Suspended: &user}
+}
+
+// 取出处理函数返回的 *RequestError，其他错误按 internal_error 处理
+func AsRequestError(err error) *RequestError {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		return reqErr
+	}
+	return &RequestError{API: ErrInternal, Cause: err, LogMessage: "Unexpected error."}
+}
+
+// 将处理函数返回的错误写入响应
+func ResponseRequestError(c echo.Context, err error) error {
+	reqErr := AsRequestError(err)
+	if reqErr.Suspended != nil {
+		return ResponseSuspended(c, *reqErr.Suspended)
+	}
+	if reqErr.LogMessage != "" {
+		return ResponseInternalServerError(c, reqErr.LogMessage, reqErr.Cause)
+	}
+	return ResponseError(c, reqErr.API, reqErr.Cause, reqErr.Details...)
+}
+
+// 错误响应的 data，RequestID 与响应头 X-Request-ID 一致，用于对照服务端日志
+type ErrorMessage struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"msg"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id"`
+}
+
+// 当前请求的关联 ID，由 RequestIDMiddleware 写入响应头
+func RequestID(c echo.Context) string {
+	return c.Response().Header().Get(echo.HeaderXRequestID)
+}
+
+/**
+ * 返回目录中的错误
+ * @param: apiErr 错误码目录中的错误
+ * @param: err 导致该错误的原始错误，只写入日志，不返回给客户端
+ * @param: details 字段级错误
+ **/
+func ResponseError(c echo.Context, apiErr *APIError, err error, details ...FieldError) error {
+	fields := []zap.Field{zap.String("code", apiErr.Code), zap.String("requestID", RequestID(c))}
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+	if apiErr.Status >= http.StatusInternalServerError {
+		logs.Error(apiErr.Message(i18n.Default), fields...)
+	} else {
+		logs.Debug(apiErr.Message(i18n.Default), fields...)
+	}
+	return responseError(c, apiErr, details)
+}
+
+/**
+ * 返回 internal_error，具体原因只写入日志
+ * @param: logMessage 日志中记录的错误描述
+ * @param: err 原始错误
+ **/
+func ResponseInternalServerError(c echo.Context, logMessage string, err error) error {
+	logs.Error(logMessage, zap.String("code", ErrInternal.Code), zap.String("requestID", RequestID(c)), zap.Error(err))
+	return responseError(c, ErrInternal, nil)
+}
+
+func responseError(c echo.Context, apiErr *APIError, details []FieldError) error {
+	lang := Lang(c)
+	translateDetails(lang, details)
+	return c.JSON(apiErr.Status, ResponseStruct{
+		Code:    apiErr.Status,
+		Message: http.StatusText(apiErr.Status),
+		Data: ErrorMessage{
+			Code:      apiErr.Code,
+			Message:   apiErr.Message(lang),
+			Details:   details,
+			RequestID: RequestID(c),
+		},
+	})
+}
+
+// 按语言写入字段级错误的 Message
+func translateDetails(lang string, details []FieldError) {
+	for i := range details {
+		if details[i].msg != nil {
+			details[i].Message = details[i].msg.Translate(lang)
+		}
+	}
+}
+
+/**
+ * 返回 invalid_parameter，附带单个字段的错误
+ * @param: key i18n 目录中的键，如 "param.invalid"，字段名作为第一个参数
+ **/
+func ResponseInvalidParameter(c echo.Context, field string, key string, args ...interface{}) error {
+	return ResponseError(c, ErrInvalidParameter, nil, newFieldError(field, "", key, args...))
+}
+
+// 检查函数返回错误时的 invalid_parameter 响应
+func responseInvalidField(c echo.Context, field string, err error) error {
+	return ResponseRequestError(c, invalidFieldError(field, err))
+}
+
+/**
+ * 处理未被处理函数写入响应的错误，包括路由不存在、方法不允许及 panic 等
+ * 状态码不在目录中时按 internal_error 处理
+ **/
+func HTTPErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	apiErr := ErrInternal
+	if he, ok := err.(*echo.HTTPError); ok {
+		if mapped, ok := statusErrors[he.Code]; ok {
+			apiErr = mapped
+		}
+	}
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(apiErr.Status)
+	} else {
+		err = ResponseError(c, apiErr, err)
+	}
+	if err != nil {
+		logs.Error("Failed to write error response.", zap.Error(err))
+	}
+}
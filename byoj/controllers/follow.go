@@ -0,0 +1,189 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+
+	"github.com/labstack/echo"
+)
+
+// 解析请求中的目标用户，返回当前用户 ID 及目标用户
+func bindTargetUser(c echo.Context) (viewerID uint32, target model.User, ok bool, err error) {
+	userRequest := model.User{}
+	_ok, err := Bind(c, &userRequest)
+	if !_ok {
+		return 0, target, false, err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return 0, target, false, ResponseError(c, ErrUnauthorized, err)
+	}
+
+	target, err = findTargetUser(claims.ID, userRequest)
+	if err != nil {
+		return 0, target, false, ResponseRequestError(c, err)
+	}
+
+	return claims.ID, target, true, nil
+}
+
+// 查找关注、拉黑等操作的目标用户，目标不能是当前用户
+func findTargetUser(viewerID uint32, userRequest model.User) (model.User, error) {
+	target, err := findUser(model.User{
+		ID:       userRequest.ID,
+		UserName: userRequest.UserName,
+		Email:    userRequest.Email,
+	})
+	if err != nil {
+		return target, err
+	}
+
+	if target.ID == viewerID {
+		return target, newRequestError(ErrSelfTarget, nil)
+	}
+
+	return target, nil
+}
+
+func UserFollowPOST(c echo.Context) error {
+	logs.Debug("POST /user/follow")
+
+	return targetUserAction(c, FollowUser)
+}
+
+func UserUnfollowPOST(c echo.Context) error {
+	logs.Debug("POST /user/unfollow")
+
+	return targetUserAction(c, UnfollowUser)
+}
+
+// 解析目标用户后执行关注等操作
+func targetUserAction(c echo.Context, action func(lang string, viewerID uint32, userRequest model.User) (StatusMessage, error)) error {
+	userRequest := model.User{}
+	_ok, err := Bind(c, &userRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	resp, err := action(Lang(c), claims.ID, userRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 关注用户，REST、GraphQL 及 gRPC 共用；已被删除或存在屏蔽关系的用户不能关注
+func FollowUser(lang string, viewerID uint32, userRequest model.User) (StatusMessage, error) {
+	target, err := findTargetUser(viewerID, userRequest)
+	if err != nil {
+		return StatusMessage{}, err
+	}
+
+	if target.Deleted {
+		return StatusMessage{}, newRequestError(ErrUserDeleted, nil)
+	}
+
+	blocked, err := model.IsBlockedBetween(viewerID, target.ID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Find block failed.", err)
+	}
+	if blocked {
+		return StatusMessage{}, newRequestError(ErrForbidden, nil)
+	}
+
+	err = model.FollowUser(viewerID, target.ID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Failed to follow user.", err)
+	}
+
+	return StatusMessage{
+		Status: i18n.Translate(lang, "status.follow"),
+	}, nil
+}
+
+// 取消关注，REST、GraphQL 及 gRPC 共用
+func UnfollowUser(lang string, viewerID uint32, userRequest model.User) (StatusMessage, error) {
+	target, err := findTargetUser(viewerID, userRequest)
+	if err != nil {
+		return StatusMessage{}, err
+	}
+
+	err = model.UnfollowUser(viewerID, target.ID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Failed to unfollow user.", err)
+	}
+
+	return StatusMessage{
+		Status: i18n.Translate(lang, "status.unfollow"),
+	}, nil
+}
+
+func UserBlockPOST(c echo.Context) error {
+	logs.Debug("POST /user/block")
+
+	viewerID, target, ok, err := bindTargetUser(c)
+	if !ok {
+		return err
+	}
+
+	err = model.BlockUser(viewerID, target.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to block user.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.block"),
+	})
+}
+
+func UserUnblockPOST(c echo.Context) error {
+	logs.Debug("POST /user/unblock")
+
+	viewerID, target, ok, err := bindTargetUser(c)
+	if !ok {
+		return err
+	}
+
+	err = model.UnblockUser(viewerID, target.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to unblock user.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.unblock"),
+	})
+}
+
+type UserSuggestionsResponse struct {
+	UserList []UserGETResponse `json:"user_list"`
+}
+
+func UserSuggestionsGET(c echo.Context) error {
+	logs.Debug("GET /user/suggestions")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	users, err := model.GetSuggestedUsers(claims.ID, 20)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get suggested users failed.", err)
+	}
+
+	resp := UserSuggestionsResponse{
+		UserList: make([]UserGETResponse, 0, len(users)),
+	}
+	for _, user := range users {
+		resp.UserList = append(resp.UserList, newUserGETResponse(user))
+	}
+	return ResponseOK(c, resp)
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/logs"
+	"context"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+)
+
+type GraphQL struct {
+	// 选择集的最大嵌套层数
+	MaxDepth int `yaml:"max-depth"`
+	// 查询的最大复杂度，每个字段计 1，列表字段的子字段按 limit 倍计算
+	MaxComplexity int `yaml:"max-complexity"`
+}
+
+var (
+	graphqlMaxDepth      = 10
+	graphqlMaxComplexity = 1000
+)
+
+// 内省查询的类型引用嵌套较深，单独限制；常用工具的完整内省查询深度为 13
+const graphqlMaxIntrospectionDepth = 15
+
+func InitGraphQL(g GraphQL) error {
+	if g.MaxDepth > 0 {
+		graphqlMaxDepth = g.MaxDepth
+	}
+	if g.MaxComplexity > 0 {
+		graphqlMaxComplexity = g.MaxComplexity
+	}
+	return nil
+}
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"         validate:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// 与 graphql.Result 结构一致，用于生成接口文档
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+/**
+ * GraphQL 接口，响应按 GraphQL 规范返回 {data, errors}，不包装在 ResponseStruct 中
+ * 错误的 extensions.code 与 REST 接口的错误码一致
+ * 执行前检查查询的深度与复杂度，超出限制时不执行
+ **/
+func GraphQLPOST(c echo.Context) error {
+	logs.Debug("POST /graphql")
+
+	graphqlRequest := GraphQLRequest{}
+	_ok, err := Bind(c, &graphqlRequest)
+	if !_ok {
+		return err
+	}
+
+	// 语法错误交给 graphql.Do 按规范格式返回
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{
+		Body: []byte(graphqlRequest.Query),
+		Name: "GraphQL request",
+	})})
+	g := newGraphQLContext(c)
+	if err == nil {
+		if err := g.checkQueryLimits(doc, graphqlRequest.Variables); err != nil {
+			return c.JSON(http.StatusOK, &graphql.Result{Errors: []gqlerrors.FormattedError{{
+				Message:    err.Error(),
+				Extensions: err.Extensions(),
+			}}})
+		}
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         graphqlSchema,
+		RequestString:  graphqlRequest.Query,
+		VariableValues: graphqlRequest.Variables,
+		OperationName:  graphqlRequest.OperationName,
+		Context:        context.WithValue(c.Request().Context(), graphqlContextKey{}, g),
+	})
+	return c.JSON(http.StatusOK, result)
+}
+
+type graphqlContextKey struct{}
+
+// 单个 GraphQL 请求内共享的状态
+type graphqlContext struct {
+	c        echo.Context
+	viewerID uint32
+	users    *userLoader
+}
+
+func newGraphQLContext(c echo.Context) *graphqlContext {
+	return &graphqlContext{
+		c:        c,
+		viewerID: GetViewerID(c),
+		users:    newUserLoader(),
+	}
+}
+
+func graphqlContextOf(ctx context.Context) *graphqlContext {
+	return ctx.Value(graphqlContextKey{}).(*graphqlContext)
+}
+
+// GraphQL 中返回给客户端的错误，extensions 中的 code 与 REST 接口一致
+type apiGraphQLError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *apiGraphQLError) Error() string {
+	return e.message
+}
+
+func (e *apiGraphQLError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+/**
+ * 构造 GraphQL 错误，与 ResponseError 一样只记录原始错误，不返回给客户端
+ * @param: err 导致该错误的原始错误，可为 nil
+ **/
+func (g *graphqlContext) error(apiErr *APIError, err error, details ...FieldError) *apiGraphQLError {
+	return g.requestError(&RequestError{API: apiErr, Cause: err, Details: details})
+}
+
+// 共用的处理函数返回的错误，错误码与 REST 接口一致
+func (g *graphqlContext) requestError(err error) *apiGraphQLError {
+	reqErr := AsRequestError(err)
+	fields := []zap.Field{zap.String("code", reqErr.API.Code), zap.String("requestID", RequestID(g.c))}
+	if reqErr.Cause != nil {
+		fields = append(fields, zap.Error(reqErr.Cause))
+	}
+	logMessage := "GraphQL resolver failed."
+	if reqErr.LogMessage != "" {
+		logMessage = reqErr.LogMessage
+	}
+	if reqErr.API.Status >= http.StatusInternalServerError {
+		logs.Error(logMessage, fields...)
+	} else {
+		logs.Debug(logMessage, fields...)
+	}
+
+	lang := Lang(g.c)
+	extensions := map[string]interface{}{
+		"code":       reqErr.API.Code,
+		"request_id": RequestID(g.c),
+	}
+	if details := reqErr.LocalizedDetails(lang); len(details) > 0 {
+		extensions["details"] = details
+	}
+	return &apiGraphQLError{message: reqErr.API.Message(lang), extensions: extensions}
+}
+
+// 写入操作与 TokenVerificationMiddleware 一样校验令牌及用户状态
+func (g *graphqlContext) authenticate() (auth.Claims, error) {
+	claims, err := VerifyToken(g.c.Request().Header.Get(echo.HeaderAuthorization))
+	if err == nil {
+		_, err = Authenticate(claims)
+	}
+	if err != nil {
+		return auth.Claims{}, g.requestError(err)
+	}
+	return claims, nil
+}
+
+/**
+ * 请求内的用户批量加载，同一层级的字段先登记 ID，首个字段取值时一次查询全部
+ * 查询按层级广度优先执行，同一层的作者只查询一次；graphql-go 在单个 goroutine 中执行，无需加锁
+ **/
+type userLoader struct {
+	pending []uint32
+	users   map[uint32]model.User
+}
+
+func newUserLoader() *userLoader {
+	return &userLoader{users: make(map[uint32]model.User)}
+}
+
+func (l *userLoader) load(g *graphqlContext, id uint32) func() (interface{}, error) {
+	if _, ok := l.users[id]; !ok {
+		l.pending = append(l.pending, id)
+	}
+	return func() (interface{}, error) {
+		if len(l.pending) > 0 {
+			ids := l.pending
+			l.pending = nil
+			found, err := model.FindUsersByIDs(ids)
+			if err != nil {
+				return nil, g.error(ErrInternal, err)
+			}
+			for _, id := range ids {
+				// 不存在的用户同样记录，避免重复查询
+				l.users[id] = found[id]
+			}
+		}
+		if user := l.users[id]; user.ID != 0 {
+			return user, nil
+		}
+		return nil, nil
+	}
+}
+
+// 已查询到的用户直接放入缓存
+func (l *userLoader) prime(user model.User) {
+	l.users[user.ID] = user
+}
@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"byoj/utils/i18n"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// 返回帖子列表的字段，复杂度按 limit 参数倍计算
+var graphqlListFields = map[string]bool{
+	"posts":        true,
+	"listTimeline": true,
+}
+
+/**
+ * 检查查询的深度与复杂度，超出限制时返回错误
+ * 根字段深度为 1；每个字段复杂度计 1，列表字段的子字段复杂度乘以 limit，未指定时按默认值计算
+ * __schema、__type 等内省字段的子树单独按 graphqlMaxIntrospectionDepth 限制深度，以免工具的内省查询被拒绝
+ * 内省类型是递归的，子树同样计入复杂度
+ **/
+func (g *graphqlContext) checkQueryLimits(doc *ast.Document, variables map[string]interface{}) *apiGraphQLError {
+	a := queryAnalyzer{
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: variables,
+	}
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			a.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	depth, complexity := 0, 0
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		d, cost := a.selectionSet(operation.SelectionSet, 1, make(map[string]bool))
+		if d > depth {
+			depth = d
+		}
+		if cost > complexity {
+			complexity = cost
+		}
+	}
+
+	if a.introspectionDepth > graphqlMaxIntrospectionDepth {
+		return g.error(ErrQueryTooDeep, nil, FieldError{
+			Field:  "query",
+			Rule:   "max_depth",
+			Limit:  graphqlMaxIntrospectionDepth,
+			Actual: a.introspectionDepth,
+			msg:    i18n.NewError("graphql.max_depth", graphqlMaxIntrospectionDepth),
+		})
+	}
+	if depth > graphqlMaxDepth {
+		return g.error(ErrQueryTooDeep, nil, FieldError{
+			Field:  "query",
+			Rule:   "max_depth",
+			Limit:  graphqlMaxDepth,
+			Actual: depth,
+			msg:    i18n.NewError("graphql.max_depth", graphqlMaxDepth),
+		})
+	}
+	if complexity > graphqlMaxComplexity {
+		return g.error(ErrQueryTooComplex, nil, FieldError{
+			Field:  "query",
+			Rule:   "max_complexity",
+			Limit:  graphqlMaxComplexity,
+			Actual: complexity,
+			msg:    i18n.NewError("graphql.max_complexity", graphqlMaxComplexity),
+		})
+	}
+	return nil
+}
+
+type queryAnalyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	// 内省字段子树的最大深度，从根字段算起
+	introspectionDepth int
+	inIntrospection    bool
+}
+
+// 返回选择集的最大深度及复杂度；visiting 为展开中的片段，循环引用由之后的校验报错
+func (a *queryAnalyzer) selectionSet(set *ast.SelectionSet, depth int, visiting map[string]bool) (int, int) {
+	if set == nil {
+		return depth - 1, 0
+	}
+	maxDepth, complexity := depth-1, 0
+	for _, selection := range set.Selections {
+		var d, cost int
+		switch s := selection.(type) {
+		case *ast.Field:
+			// 进入内省字段的子树时深度记入 introspectionDepth，不计入普通查询的深度
+			introspection := !a.inIntrospection && s.SelectionSet != nil && strings.HasPrefix(s.Name.Value, "__")
+			if introspection {
+				a.inIntrospection = true
+			}
+			childDepth, childCost := a.selectionSet(s.SelectionSet, depth+1, visiting)
+			d = depth
+			if childDepth > d {
+				d = childDepth
+			}
+			if introspection {
+				a.inIntrospection = false
+				if d > a.introspectionDepth {
+					a.introspectionDepth = d
+				}
+				d = depth
+			}
+			cost = 1 + childCost*a.multiplier(s)
+		case *ast.InlineFragment:
+			d, cost = a.selectionSet(s.SelectionSet, depth, visiting)
+		case *ast.FragmentSpread:
+			fragment, ok := a.fragments[s.Name.Value]
+			if !ok || visiting[s.Name.Value] {
+				continue
+			}
+			visiting[s.Name.Value] = true
+			d, cost = a.selectionSet(fragment.SelectionSet, depth, visiting)
+			delete(visiting, s.Name.Value)
+		}
+		if d > maxDepth {
+			maxDepth = d
+		}
+		complexity += cost
+	}
+	return maxDepth, complexity
+}
+
+func (a *queryAnalyzer) multiplier(field *ast.Field) int {
+	if !graphqlListFields[field.Name.Value] {
+		return 1
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(v.Value); err == nil && n > 0 && n <= graphqlMaxLimit {
+				return n
+			}
+		case *ast.Variable:
+			// JSON 中的数字解析为 float64
+			if n, ok := a.variables[v.Name.Value].(float64); ok && n > 0 && n <= graphqlMaxLimit {
+				return int(n)
+			}
+		}
+		// 无法确定时按上限计算
+		return graphqlMaxLimit
+	}
+	return graphqlDefaultLimit
+}
@@ -0,0 +1,367 @@
+package controllers
+
+import (
+	"byoj/model"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"gorm.io/gorm"
+)
+
+const (
+	graphqlDefaultLimit = 20
+	graphqlMaxLimit     = 100
+)
+
+/**
+ * GraphQL schema，读取直接查询 model，可见性与对应的 REST 接口一致
+ * 写入与 REST 接口调用相同的处理函数，鉴权及校验规则不在此重复实现
+ **/
+var graphqlSchema = newGraphQLSchema()
+
+var postOrderEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "PostOrder",
+	Values: graphql.EnumValueConfigMap{
+		"TIME":   {Value: "time"},
+		"RANDOM": {Value: "random"},
+	},
+})
+
+// 列表字段的公共参数
+var postListArgs = graphql.FieldConfigArgument{
+	"limit":     {Type: graphql.Int, DefaultValue: graphqlDefaultLimit, Description: "Maximum number of posts, at most 100."},
+	"startTime": {Type: graphql.Int, DefaultValue: 0, Description: "Unix time; only posts at or before it are returned."},
+	"orderBy":   {Type: postOrderEnum, DefaultValue: "time"},
+}
+
+func newGraphQLSchema() graphql.Schema {
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":        userField(graphql.NewNonNull(graphql.Int), func(u model.User) interface{} { return u.ID }),
+			"userName":  userField(graphql.NewNonNull(graphql.String), func(u model.User) interface{} { return u.UserName }),
+			"realName":  userField(graphql.NewNonNull(graphql.String), func(u model.User) interface{} { return u.RealName }),
+			"bio":       userField(graphql.NewNonNull(graphql.String), func(u model.User) interface{} { return u.Bio }),
+			"verified":  userField(graphql.NewNonNull(graphql.Boolean), func(u model.User) interface{} { return u.Verified }),
+			"deleted":   userField(graphql.NewNonNull(graphql.Boolean), func(u model.User) interface{} { return u.Deleted }),
+			"suspended": userField(graphql.NewNonNull(graphql.Boolean), func(u model.User) interface{} { return u.IsSuspended(time.Now()) }),
+			"email": &graphql.Field{
+				Type:        graphql.String,
+				Description: "Only visible to the user themselves, null for other viewers.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := p.Source.(model.User)
+					if graphqlContextOf(p.Context).viewerID != user.ID {
+						return nil, nil
+					}
+					return user.Email, nil
+				},
+			},
+		},
+	})
+
+	postType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Post",
+		Fields: graphql.Fields{
+			"id":             postField(graphql.NewNonNull(graphql.Int), func(p PostResponse) interface{} { return p.PostID }),
+			"time":           postField(graphql.NewNonNull(graphql.Int), func(p PostResponse) interface{} { return p.Time }),
+			"content":        postField(graphql.NewNonNull(graphql.String), func(p PostResponse) interface{} { return p.Content }),
+			"text":           postField(graphql.NewNonNull(graphql.String), func(p PostResponse) interface{} { return p.Text }),
+			"html":           postField(graphql.NewNonNull(graphql.String), func(p PostResponse) interface{} { return p.HTML }),
+			"isPublic":       postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.IsPublic }),
+			"sensitive":      postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.Sensitive }),
+			"collapsed":      postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.Collapsed }),
+			"contentWarning": postField(graphql.NewNonNull(graphql.String), func(p PostResponse) interface{} { return p.ContentWarning }),
+			"sensitiveMedia": postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.SensitiveMedia }),
+			"bookmarked":     postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.Bookmarked }),
+			"liked":          postField(graphql.NewNonNull(graphql.Boolean), func(p PostResponse) interface{} { return p.Liked }),
+			"likeCount":      postField(graphql.NewNonNull(graphql.Int), func(p PostResponse) interface{} { return p.LikeCount }),
+			"author": &graphql.Field{
+				Type: userType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					g := graphqlContextOf(p.Context)
+					return g.users.load(g, p.Source.(PostResponse).AuthorID), nil
+				},
+			},
+		},
+	})
+
+	userType.AddFieldConfig("posts", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+		Description: "Posts by this user, same as GET /v1/post?user_id=.",
+		Args:        postListArgs,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			g := graphqlContextOf(p.Context)
+			user := p.Source.(model.User)
+			return g.postsList(p.Args, func(startTime time.Time, orderBy string, limit int) ([]model.Post, error) {
+				return model.GetPostsList(user.ID, startTime, false, orderBy, nil, limit)
+			})
+		},
+	})
+
+	postPayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "PostPayload",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"post":   &graphql.Field{Type: postType, Description: "Null when the post is held for review."},
+		},
+	})
+	userPayloadType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "UserPayload",
+		Fields: graphql.Fields{
+			"status": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"user":   &graphql.Field{Type: userType},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"viewer": &graphql.Field{
+				Type:        userType,
+				Description: "The user of the access token, null when not logged in.",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					g := graphqlContextOf(p.Context)
+					if g.viewerID == 0 {
+						return nil, nil
+					}
+					return g.users.load(g, g.viewerID), nil
+				},
+			},
+			"user": &graphql.Field{
+				Type:        userType,
+				Description: "Get a user by id or userName.",
+				Args: graphql.FieldConfigArgument{
+					"id":       {Type: graphql.Int},
+					"userName": {Type: graphql.String},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphqlContextOf(p.Context).findUser(p.Args)
+				},
+			},
+			"post": &graphql.Field{
+				Type: postType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.Int)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return graphqlContextOf(p.Context).findPost(uint32(p.Args["id"].(int)))
+				},
+			},
+			"posts": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+				Description: "Timeline of all users, same as GET /v1/post.",
+				Args:        postListArgs,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					g := graphqlContextOf(p.Context)
+					return g.postsList(p.Args, func(startTime time.Time, orderBy string, limit int) ([]model.Post, error) {
+						return model.GetPostsList(0, startTime, false, orderBy, nil, limit)
+					})
+				},
+			},
+			"listTimeline": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(postType))),
+				Description: "Posts from members of a list, same as GET /v1/list/timeline.",
+				Args: graphql.FieldConfigArgument{
+					"listId":    {Type: graphql.NewNonNull(graphql.Int)},
+					"limit":     postListArgs["limit"],
+					"startTime": postListArgs["startTime"],
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					g := graphqlContextOf(p.Context)
+					list, err := model.FindListByID(uint32(p.Args["listId"].(int)))
+					if err != nil {
+						if err == gorm.ErrRecordNotFound {
+							return nil, g.error(ErrListNotFound, err)
+						}
+						return nil, g.error(ErrInternal, err)
+					}
+					if !list.IsPublic && list.OwnerID != g.viewerID {
+						return nil, g.error(ErrListNotFound, nil)
+					}
+					p.Args["orderBy"] = "time"
+					return g.postsList(p.Args, func(startTime time.Time, orderBy string, limit int) ([]model.Post, error) {
+						return model.GetListPostsList(list.ID, g.viewerID, startTime, orderBy, nil, limit)
+					})
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createPost": &graphql.Field{
+				Type:        graphql.NewNonNull(postPayloadType),
+				Description: "Same as POST /v1/post.",
+				Args: graphql.FieldConfigArgument{
+					"content":        {Type: graphql.NewNonNull(graphql.String)},
+					"contentWarning": {Type: graphql.String, DefaultValue: ""},
+					"sensitiveMedia": {Type: graphql.Boolean, DefaultValue: false},
+					"mediaIds":       {Type: graphql.NewList(graphql.NewNonNull(graphql.Int))},
+				},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					g := graphqlContextOf(p.Context)
+					claims, err := g.authenticate()
+					if err != nil {
+						return nil, err
+					}
+					request := PostCreateRequest{
+						AuthorID:       claims.ID,
+						Content:        p.Args["content"].(string),
+						ContentWarning: p.Args["contentWarning"].(string),
+						SensitiveMedia: p.Args["sensitiveMedia"].(bool),
+					}
+					if ids, ok := p.Args["mediaIds"].([]interface{}); ok {
+						for _, id := range ids {
+							request.MediaIDs = append(request.MediaIDs, uint32(id.(int)))
+						}
+					}
+					resp, err := CreatePost(Lang(g.c), claims, request)
+					if err != nil {
+						return nil, g.requestError(err)
+					}
+					payload := map[string]interface{}{"status": resp.Status}
+					if !resp.Held {
+						post, err := g.findPost(resp.PostID)
+						if err != nil {
+							return nil, err
+						}
+						payload["post"] = post
+					}
+					return payload, nil
+				},
+			},
+			"likePost":     postMutation(postPayloadType, "/v1/post/like", LikePost),
+			"unlikePost":   postMutation(postPayloadType, "/v1/post/unlike", UnlikePost),
+			"followUser":   userMutation(userPayloadType, "/v1/user/follow", FollowUser),
+			"unfollowUser": userMutation(userPayloadType, "/v1/user/unfollow", UnfollowUser),
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+	if err != nil {
+		panic(err)
+	}
+	return schema
+}
+
+func userField(t graphql.Output, get func(model.User) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(model.User)), nil
+		},
+	}
+}
+
+func postField(t graphql.Output, get func(PostResponse) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return get(p.Source.(PostResponse)), nil
+		},
+	}
+}
+
+// 以 post_id 调用与 REST 接口 path 相同的处理函数，返回操作后的帖子
+func postMutation(payload graphql.Output, path string, action func(lang string, viewerID uint32, likeRequest LikeRequest) (StatusMessage, error)) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(payload),
+		Description: "Same as POST " + path + ".",
+		Args:        graphql.FieldConfigArgument{"postId": {Type: graphql.NewNonNull(graphql.Int)}},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			g := graphqlContextOf(p.Context)
+			claims, err := g.authenticate()
+			if err != nil {
+				return nil, err
+			}
+			postID := uint32(p.Args["postId"].(int))
+			resp, err := action(Lang(g.c), claims.ID, LikeRequest{PostID: postID})
+			if err != nil {
+				return nil, g.requestError(err)
+			}
+			post, err := g.findPost(postID)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"status": resp.Status, "post": post}, nil
+		},
+	}
+}
+
+// 以 user_id 调用与 REST 接口 path 相同的处理函数，返回目标用户
+func userMutation(payload graphql.Output, path string, action func(lang string, viewerID uint32, userRequest model.User) (StatusMessage, error)) *graphql.Field {
+	return &graphql.Field{
+		Type:        graphql.NewNonNull(payload),
+		Description: "Same as POST " + path + ".",
+		Args:        graphql.FieldConfigArgument{"userId": {Type: graphql.NewNonNull(graphql.Int)}},
+		Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			g := graphqlContextOf(p.Context)
+			claims, err := g.authenticate()
+			if err != nil {
+				return nil, err
+			}
+			userID := uint32(p.Args["userId"].(int))
+			resp, err := action(Lang(g.c), claims.ID, model.User{ID: userID})
+			if err != nil {
+				return nil, g.requestError(err)
+			}
+			return map[string]interface{}{"status": resp.Status, "user": g.users.load(g, userID)}, nil
+		},
+	}
+}
+
+// 按 id、userName 的顺序查找；不支持按邮箱查找，以免被用来确认邮箱是否已注册
+func (g *graphqlContext) findUser(args map[string]interface{}) (interface{}, error) {
+	var user model.User
+	var err error
+	if id, ok := args["id"].(int); ok {
+		user, err = model.FindUserByID(uint32(id))
+	} else if userName, ok := args["userName"].(string); ok && userName != "" {
+		user, err = model.FindUserByName(userName)
+	} else {
+		return nil, g.error(ErrInvalidParameter, nil, newFieldError("id", "required", "param.required"))
+	}
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, g.error(ErrUserNotFound, err)
+		}
+		return nil, g.error(ErrInternal, err)
+	}
+	g.users.prime(user)
+	return user, nil
+}
+
+// 待审核的帖子不可见，非公开帖子仅作者可见
+func (g *graphqlContext) findPost(postID uint32) (interface{}, error) {
+	post, err := model.FindPostByPostID(postID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, g.error(ErrPostNotFound, err)
+		}
+		return nil, g.error(ErrInternal, err)
+	}
+	if post.Held || !post.IsPublic && post.AuthorID != g.viewerID {
+		return nil, g.error(ErrPostNotFound, nil)
+	}
+	posts := buildPostResponses([]model.Post{post}, nil, g.viewerID)
+	if len(posts) == 0 {
+		// 被屏蔽词隐藏
+		return nil, g.error(ErrPostNotFound, nil)
+	}
+	return posts[0], nil
+}
+
+// 校验列表参数后查询帖子
+func (g *graphqlContext) postsList(args map[string]interface{}, find func(startTime time.Time, orderBy string, limit int) ([]model.Post, error)) (interface{}, error) {
+	limit := args["limit"].(int)
+	if limit <= 0 || limit > graphqlMaxLimit {
+		detail := newFieldError("limit", "lte", "param.max", graphqlMaxLimit)
+		detail.Limit = graphqlMaxLimit
+		detail.Actual = limit
+		return nil, g.error(ErrInvalidParameter, nil, detail)
+	}
+	posts, err := find(time.Unix(int64(args["startTime"].(int)), 0), args["orderBy"].(string), limit)
+	if err != nil {
+		return nil, g.error(ErrInternal, err)
+	}
+	return buildPostResponses(posts, nil, g.viewerID), nil
+}
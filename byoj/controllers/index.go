@@ -7,7 +7,8 @@ import (
 )
 
 type documentLink struct {
-	Doc string `json:"document"`
+	Doc     string `json:"document"`
+	OpenAPI string `json:"openapi"`
 }
 
 type link struct {
@@ -19,7 +20,8 @@ func IndexGET(c echo.Context) error {
 
 	return ResponseOK(c, link{
 		Link: documentLink{
-			Doc: "// Document link here.",
+			Doc:     "/docs",
+			OpenAPI: "/openapi.json",
 		},
 	})
 }
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+type LikeRequest struct {
+	PostID uint32 `json:"post_id" validate:"required"`
+}
+
+func PostLikePOST(c echo.Context) error {
+	logs.Debug("POST /post/like")
+
+	return likeAction(c, LikePost)
+}
+
+func PostUnlikePOST(c echo.Context) error {
+	logs.Debug("POST /post/unlike")
+
+	return likeAction(c, UnlikePost)
+}
+
+// 解析帖子 ID 后执行点赞或取消点赞
+func likeAction(c echo.Context, action func(lang string, viewerID uint32, likeRequest LikeRequest) (StatusMessage, error)) error {
+	likeRequest := LikeRequest{}
+	_ok, err := Bind(c, &likeRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	resp, err := action(Lang(c), claims.ID, likeRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 点赞帖子，REST、GraphQL 及 gRPC 共用；非公开帖子仅作者可以点赞
+func LikePost(lang string, viewerID uint32, likeRequest LikeRequest) (StatusMessage, error) {
+	if err := validateFields(likeRequest); err != nil {
+		return StatusMessage{}, err
+	}
+
+	post, err := model.FindPostByPostID(likeRequest.PostID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return StatusMessage{}, newRequestError(ErrPostNotFound, err)
+		}
+		return StatusMessage{}, internalRequestError("Find post failed.", err)
+	}
+	if !post.IsPublic && post.AuthorID != viewerID {
+		return StatusMessage{}, newRequestError(ErrForbidden, nil)
+	}
+
+	blocked, err := model.IsBlockedBetween(viewerID, post.AuthorID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Find block failed.", err)
+	}
+	if blocked {
+		return StatusMessage{}, newRequestError(ErrForbidden, nil)
+	}
+
+	err = model.CreateLike(viewerID, post.ID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Failed to create like.", err)
+	}
+
+	return StatusMessage{
+		Status: i18n.Translate(lang, "status.like"),
+	}, nil
+}
+
+// 取消点赞，REST、GraphQL 及 gRPC 共用
+func UnlikePost(lang string, viewerID uint32, likeRequest LikeRequest) (StatusMessage, error) {
+	if err := validateFields(likeRequest); err != nil {
+		return StatusMessage{}, err
+	}
+
+	err := model.DeleteLike(viewerID, likeRequest.PostID)
+	if err != nil {
+		return StatusMessage{}, internalRequestError("Failed to delete like.", err)
+	}
+
+	return StatusMessage{
+		Status: i18n.Translate(lang, "status.unlike"),
+	}, nil
+}
@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+type ListCreateRequest struct {
+	Name        string `json:"name"        validate:"required,max=64"`
+	Description string `json:"description" validate:"max=500"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type ListResponse struct {
+	ListID      uint32 `json:"list_id"`
+	OwnerID     uint32 `json:"user_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+type ListGetResponse struct {
+	ListResponse
+	MemberList []UserGETResponse `json:"member_list"`
+}
+
+type ListsGetResponse struct {
+	Lists []ListResponse `json:"list_list"`
+}
+
+type ListTimelineGetRequest struct {
+	ListID    uint32 `json:"list_id"    query:"list_id"    validate:"required"`
+	Limit     int    `json:"limit"      query:"limit"      validate:"gte=0,lte=100"`
+	StartTime int64  `json:"start_time" query:"start_time"`
+	// 上一页的 next_cursor
+	Cursor string `json:"cursor" query:"cursor"`
+}
+
+type ListMemberRequest struct {
+	ListID   uint32 `json:"list_id" validate:"required"`
+	UserID   uint32 `json:"user_id"`
+	UserName string `json:"user_name"`
+	Email    string `json:"email"`
+}
+
+func newListResponse(list model.List) ListResponse {
+	return ListResponse{
+		ListID:      list.ID,
+		OwnerID:     list.OwnerID,
+		Name:        list.Name,
+		Description: list.Description,
+		IsPublic:    list.IsPublic,
+	}
+}
+
+// 查找列表，非公开列表仅创建者可见
+func findVisibleList(c echo.Context, listID uint32, viewerID uint32) (list model.List, ok bool, err error) {
+	list, err = model.FindListByID(listID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return list, false, ResponseError(c, ErrListNotFound, err)
+		}
+		return list, false, ResponseInternalServerError(c, "Find list failed.", err)
+	}
+	if !list.IsPublic && list.OwnerID != viewerID {
+		return list, false, ResponseError(c, ErrListNotFound, gorm.ErrRecordNotFound)
+	}
+	return list, true, nil
+}
+
+func parseListID(c echo.Context) (uint32, error) {
+	listID, err := strconv.ParseUint(c.QueryParam("list_id"), 10, 32)
+	if err != nil || listID == 0 {
+		return 0, i18n.NewError("param.invalid", "list_id")
+	}
+	return uint32(listID), nil
+}
+
+func ListPOST(c echo.Context) error {
+	logs.Debug("POST /list")
+
+	listRequest := ListCreateRequest{}
+	_ok, err := Bind(c, &listRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	list, err := model.CreateList(claims.ID, listRequest.Name, listRequest.Description, listRequest.IsPublic)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to create list into database.", err)
+	}
+
+	return ResponseOK(c, newListResponse(list))
+}
+
+func ListGET(c echo.Context) error {
+	logs.Debug("GET /list")
+
+	listID, err := parseListID(c)
+	if err != nil {
+		return responseInvalidField(c, "list_id", err)
+	}
+
+	list, ok, err := findVisibleList(c, listID, GetViewerID(c))
+	if !ok {
+		return err
+	}
+
+	members, err := model.GetListMembers(list.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get list members failed.", err)
+	}
+
+	resp := ListGetResponse{
+		ListResponse: newListResponse(list),
+		MemberList:   make([]UserGETResponse, 0, len(members)),
+	}
+	for _, member := range members {
+		resp.MemberList = append(resp.MemberList, newUserGETResponse(member))
+	}
+	return ResponseOK(c, resp)
+}
+
+// 获取用户创建的列表，非本人只能看到公开列表
+func ListsGET(c echo.Context) error {
+	logs.Debug("GET /list/user")
+
+	ownerID, err := strconv.ParseUint(c.QueryParam("user_id"), 10, 32)
+	if err != nil {
+		return ResponseInvalidParameter(c, "user_id", "param.invalid")
+	}
+
+	lists, err := model.GetListsByOwner(uint32(ownerID), uint32(ownerID) != GetViewerID(c))
+	if err != nil {
+		return ResponseInternalServerError(c, "Get lists failed.", err)
+	}
+
+	resp := ListsGetResponse{
+		Lists: make([]ListResponse, 0, len(lists)),
+	}
+	for _, list := range lists {
+		resp.Lists = append(resp.Lists, newListResponse(list))
+	}
+	return ResponseOK(c, resp)
+}
+
+// 解析成员请求，仅列表创建者可以修改成员
+func bindListMember(c echo.Context) (list model.List, member model.User, ok bool, err error) {
+	memberRequest := ListMemberRequest{}
+	_ok, err := Bind(c, &memberRequest)
+	if !_ok {
+		return list, member, false, err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return list, member, false, ResponseError(c, ErrUnauthorized, err)
+	}
+
+	list, ok, err = findVisibleList(c, memberRequest.ListID, claims.ID)
+	if !ok {
+		return list, member, false, err
+	}
+	if list.OwnerID != claims.ID {
+		return list, member, false, ResponseError(c, ErrForbidden, nil)
+	}
+
+	member, err = findUser(model.User{
+		ID:       memberRequest.UserID,
+		UserName: memberRequest.UserName,
+		Email:    memberRequest.Email,
+	})
+	if err != nil {
+		return list, member, false, ResponseRequestError(c, err)
+	}
+
+	return list, member, true, nil
+}
+
+func ListAddPOST(c echo.Context) error {
+	logs.Debug("POST /list/add")
+
+	list, member, ok, err := bindListMember(c)
+	if !ok {
+		return err
+	}
+
+	if member.Deleted {
+		return ResponseError(c, ErrUserDeleted, nil)
+	}
+
+	count, err := model.CountListMembers(list.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Count list members failed.", err)
+	}
+	if count >= model.ListMemberLimit {
+		return ResponseError(c, ErrTooManyListMembers, nil)
+	}
+
+	err = model.AddListMember(list.ID, member.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to add list member.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.list_add"),
+	})
+}
+
+func ListRemovePOST(c echo.Context) error {
+	logs.Debug("POST /list/remove")
+
+	list, member, ok, err := bindListMember(c)
+	if !ok {
+		return err
+	}
+
+	err = model.RemoveListMember(list.ID, member.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to remove list member.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.list_remove"),
+	})
+}
+
+func ListTimelineGET(c echo.Context) error {
+	logs.Debug("GET /list/timeline")
+
+	timelineRequest := ListTimelineGetRequest{}
+	_ok, err := Bind(c, &timelineRequest)
+	if !_ok {
+		return err
+	}
+
+	viewerID := GetViewerID(c)
+	list, ok, err := findVisibleList(c, timelineRequest.ListID, viewerID)
+	if !ok {
+		return err
+	}
+
+	cursor, err := model.DecodePostCursor(timelineRequest.Cursor)
+	if err != nil {
+		return ResponseInvalidParameter(c, "cursor", "param.invalid")
+	}
+	limit := timelineRequest.Limit
+	if limit == 0 {
+		limit = 20
+	}
+
+	posts, err := model.GetListPostsList(list.ID, viewerID, time.Unix(timelineRequest.StartTime, 0), "time", cursor, limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get list posts failed.", err)
+	}
+
+	return ResponseOK(c, newPostGetResponse(posts, nil, viewerID, "time", limit))
+}
@@ -0,0 +1,20 @@
+package controllers
+
+import (
+	"byoj/utils/i18n"
+
+	"github.com/labstack/echo"
+)
+
+// 当前请求的语言，由 LocaleMiddleware 写入响应头；未经过该中间件时直接按请求头协商
+func Lang(c echo.Context) string {
+	if lang := c.Response().Header().Get("Content-Language"); lang != "" {
+		return lang
+	}
+	return i18n.Negotiate(c.Request().Header.Get("Accept-Language"))
+}
+
+// 按当前请求的语言翻译 i18n 目录中的文本
+func Translate(c echo.Context, key string, args ...interface{}) string {
+	return i18n.Translate(Lang(c), key, args...)
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/media"
+	"byoj/model"
+	"byoj/storage"
+	"byoj/utils/logs"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	altTextMaxLength = 1000
+	// multipart 表单中除文件外其他字段所占空间的余量
+	multipartOverhead = 1 << 20
+)
+
+type MediaResponse struct {
+	MediaID     uint32 `json:"media_id"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	AltText     string `json:"alt_text"`
+	Blurhash    string `json:"blurhash"`
+}
+
+func newMediaResponse(attachment model.Attachment) MediaResponse {
+	return MediaResponse{
+		MediaID:     attachment.ID,
+		URL:         "/v1/media/" + strconv.FormatUint(uint64(attachment.ID), 10),
+		ContentType: attachment.ContentType,
+		Size:        attachment.Size,
+		Width:       attachment.Width,
+		Height:      attachment.Height,
+		AltText:     attachment.AltText,
+		Blurhash:    attachment.Blurhash,
+	}
+}
+
+// media.Inspect 或 media.DecodeImage 失败时的响应
+func responseMediaError(c echo.Context, err error) error {
+	switch err {
+	case media.ErrTooLarge:
+		return ResponseError(c, ErrPayloadTooLarge, err)
+	case media.ErrUnsupportedType:
+		return ResponseError(c, ErrUnsupportedMedia, err)
+	}
+	return ResponseError(c, ErrInvalidUpload, err)
+}
+
+func randomKey(prefix string, extension string) (string, error) {
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(buf) + extension, nil
+}
+
+func MediaPOST(c echo.Context) error {
+	logs.Debug("POST /media")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	req := c.Request()
+	req.Body = http.MaxBytesReader(c.Response(), req.Body, media.MaxUploadSize()+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return ResponseError(c, ErrInvalidUpload, err)
+	}
+	altText := c.FormValue("alt_text")
+	if len([]rune(altText)) > altTextMaxLength {
+		return ResponseInvalidParameter(c, "alt_text", "param.max_length", altTextMaxLength)
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ResponseError(c, ErrInvalidUpload, err)
+	}
+	defer file.Close()
+
+	info, err := media.Inspect(file, fileHeader.Size)
+	if err != nil {
+		return responseMediaError(c, err)
+	}
+
+	key, err := randomKey("media/", info.Extension)
+	if err != nil {
+		return ResponseInternalServerError(c, "Generate media key failed.", err)
+	}
+	err = storage.GetStore().Put(req.Context(), key, file, info.Size, info.ContentType)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to store media file.", err)
+	}
+
+	attachment, err := model.CreateAttachment(model.Attachment{
+		OwnerID:     claims.ID,
+		Key:         key,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		Width:       info.Width,
+		Height:      info.Height,
+		AltText:     altText,
+		Blurhash:    info.Blurhash,
+	})
+	if err != nil {
+		if derr := storage.GetStore().Delete(req.Context(), key); derr != nil {
+			logs.Warn("Delete orphaned media file failed.", zap.String("key", key), zap.Error(derr))
+		}
+		return ResponseInternalServerError(c, "Failed to create media into database.", err)
+	}
+
+	return ResponseOK(c, newMediaResponse(attachment))
+}
+
+// 附件内容不可变，公开附件允许共享缓存长期保存，非公开帖子的附件仅允许私有缓存
+func MediaGET(c echo.Context) error {
+	logs.Debug("GET /media/:id")
+
+	mediaID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return ResponseInvalidParameter(c, "id", "param.invalid")
+	}
+
+	attachment, err := model.FindAttachmentByID(uint32(mediaID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return ResponseInternalServerError(c, "Find media failed.", err)
+	}
+
+	cacheControl := "public, max-age=31536000, immutable"
+	if attachment.PostID == 0 {
+		if attachment.OwnerID != GetViewerID(c) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		cacheControl = "private, max-age=31536000, immutable"
+	} else {
+		post, err := model.FindPostByPostID(attachment.PostID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return c.NoContent(http.StatusNotFound)
+			}
+			return ResponseInternalServerError(c, "Find post failed.", err)
+		}
+		if !post.IsPublic {
+			if post.AuthorID != GetViewerID(c) {
+				return c.NoContent(http.StatusNotFound)
+			}
+			cacheControl = "private, max-age=31536000, immutable"
+		}
+	}
+
+	etag := `"` + attachment.Key + `"`
+	header := c.Response().Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("ETag", etag)
+	header.Set("X-Content-Type-Options", "nosniff")
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	r, err := storage.GetStore().Get(c.Request().Context(), attachment.Key)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to read media file.", err)
+	}
+	defer r.Close()
+
+	header.Set("Content-Length", strconv.FormatInt(attachment.Size, 10))
+	return c.Stream(http.StatusOK, attachment.ContentType, r)
+}
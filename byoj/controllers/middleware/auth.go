@@ -2,37 +2,39 @@ package middleware
 
 import (
 	"byoj/controllers"
-	"byoj/controllers/auth"
 	"byoj/model"
-	"time"
 
 	"github.com/labstack/echo"
-	"gorm.io/gorm"
 )
 
+// TokenVerificationMiddleware 查到的用户，供之后的中间件使用
+const userContextKey = "auth_user"
+
 func TokenVerificationMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		claims, err := auth.GetClaimsFromHeader(c)
+		claims, err := controllers.VerifyToken(c.Request().Header.Get(echo.HeaderAuthorization))
 		if err != nil {
-			return controllers.ResponseUnauthorized(c, "Invalid bearer token in header.", err)
+			return controllers.ResponseRequestError(c, err)
 		}
-		if claims.Valid() != nil {
-			return controllers.ResponseUnauthorized(c, "Invalid jwt token.", claims.Valid())
+		user, err := controllers.Authenticate(claims)
+		if err != nil {
+			return controllers.ResponseRequestError(c, err)
 		}
 
-		if claims.ExpiresAt < time.Now().Unix() {
-			return controllers.ResponseUnauthorized(c, "Token expired.", nil)
-		}
+		c.Set(userContextKey, user)
+		return next(c)
+	}
+}
 
-		user, err := model.FindUserByID(claims.ID)
-		if err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return controllers.ResponseUnauthorized(c, "User in token not found.", err)
-			}
-			return controllers.ResponseInternalServerError(c, "Find user by ID failed.", err)
+// Must be used after TokenVerificationMiddleware
+func AdminVerificationMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		user, ok := c.Get(userContextKey).(model.User)
+		if !ok {
+			return controllers.ResponseError(c, controllers.ErrUnauthorized, nil)
 		}
-		if user.UserName != claims.UserName {
-			return controllers.ResponseUnauthorized(c, "UserID does not match username.", err)
+		if !user.IsAdmin {
+			return controllers.ResponseError(c, controllers.ErrAdminRequired, nil)
 		}
 
 		return next(c)
@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"byoj/utils/i18n"
+
+	"github.com/labstack/echo"
+)
+
+/**
+ * 按 Accept-Language 协商响应语言，写入响应头 Content-Language
+ * 响应内容随请求头变化，同时声明 Vary 以免共享缓存返回其他语言的响应
+ **/
+func LocaleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		lang := i18n.Negotiate(c.Request().Header.Get("Accept-Language"))
+		header := c.Response().Header()
+		header.Set("Content-Language", lang)
+		header.Add(echo.HeaderVary, "Accept-Language")
+		return next(c)
+	}
+}
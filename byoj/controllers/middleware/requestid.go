@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/labstack/echo"
+)
+
+const maxRequestIDLength = 64
+
+/**
+ * 为每个请求分配关联 ID，写入响应头 X-Request-ID
+ * 客户端传入合法的 X-Request-ID 时沿用，否则重新生成，避免任意内容写入日志
+ **/
+func RequestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := ResolveRequestID(c.Request().Header.Get(echo.HeaderXRequestID))
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		return next(c)
+	}
+}
+
+// 客户端传入的 ID 合法时沿用，否则重新生成；gRPC 的 metadata 同样按此处理
+func ResolveRequestID(id string) string {
+	if !validRequestID(id) {
+		return newRequestID()
+	}
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		ch := id[i]
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	// crypto/rand 读取失败时退化为全零 ID，不影响请求处理
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
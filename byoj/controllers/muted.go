@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/policy"
+	"byoj/utils/logs"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+type MutedWordRequest struct {
+	Word string `json:"word" validate:"required,max=200"`
+}
+
+type MutedWordsGetResponse struct {
+	WordList []string `json:"word_list"`
+}
+
+func UserMutedPOST(c echo.Context) error {
+	logs.Debug("POST /user/muted")
+
+	mutedRequest := MutedWordRequest{}
+	_ok, err := Bind(c, &mutedRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	word := strings.TrimSpace(mutedRequest.Word)
+	_, err = policy.CompileRule(policy.Rule{Pattern: word})
+	if err != nil {
+		return responseInvalidField(c, "word", patternError("word", err))
+	}
+
+	err = model.CreateMutedWord(claims.ID, word)
+	if err == model.ErrTooManyMutedWords {
+		return ResponseError(c, ErrTooManyMutedWords, err)
+	}
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to create muted word.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.mute"),
+	})
+}
+
+func UserMutedGET(c echo.Context) error {
+	logs.Debug("GET /user/muted")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	words, err := model.GetMutedWords(claims.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get muted words failed.", err)
+	}
+
+	list := make([]string, 0, len(words))
+	for _, w := range words {
+		list = append(list, w.Word)
+	}
+	return ResponseOK(c, MutedWordsGetResponse{
+		WordList: list,
+	})
+}
+
+func UserMutedRemovePOST(c echo.Context) error {
+	logs.Debug("POST /user/muted/remove")
+
+	mutedRequest := MutedWordRequest{}
+	_ok, err := Bind(c, &mutedRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	err = model.DeleteMutedWord(claims.ID, strings.TrimSpace(mutedRequest.Word))
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to delete muted word.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.unmute"),
+	})
+}
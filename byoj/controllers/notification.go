@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/logs"
+	"strconv"
+
+	"github.com/labstack/echo"
+)
+
+type NotificationResponse struct {
+	NotificationID uint32 `json:"notification_id"`
+	Type           string `json:"type"`
+	PostID         uint32 `json:"post_id"`
+	Content        string `json:"content"`
+	Read           bool   `json:"read"`
+	Time           int64  `json:"time"`
+}
+
+type NotificationGetResponse struct {
+	NotificationList []NotificationResponse `json:"notification_list"`
+	NextCursor       string                 `json:"next_cursor"`
+}
+
+type NotificationReadRequest struct {
+	UntilID uint32 `json:"notification_id"`
+}
+
+func UserNotificationsGET(c echo.Context) error {
+	logs.Debug("GET /user/notifications")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	var cursor uint64
+	if s := c.QueryParam("cursor"); s != "" {
+		cursor, err = strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return ResponseInvalidParameter(c, "cursor", "param.invalid")
+		}
+	}
+	limit := 20
+	if s := c.QueryParam("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > 100 {
+			return ResponseInvalidParameter(c, "limit", "param.invalid")
+		}
+	}
+
+	notifications, err := model.GetNotifications(claims.ID, uint32(cursor), limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get notifications failed.", err)
+	}
+
+	resp := NotificationGetResponse{
+		NotificationList: make([]NotificationResponse, 0, len(notifications)),
+	}
+	for _, notification := range notifications {
+		resp.NotificationList = append(resp.NotificationList, NotificationResponse{
+			NotificationID: notification.ID,
+			Type:           notification.Type,
+			PostID:         notification.PostID,
+			Content:        notificationContent(c, notification),
+			Read:           notification.Read,
+			Time:           notification.CreatedAt.Unix(),
+		})
+	}
+	if len(notifications) == limit {
+		resp.NextCursor = strconv.FormatUint(uint64(notifications[len(notifications)-1].ID), 10)
+	}
+	return ResponseOK(c, resp)
+}
+
+func UserNotificationsReadPOST(c echo.Context) error {
+	logs.Debug("POST /user/notifications/read")
+
+	readRequest := NotificationReadRequest{}
+	_ok, err := Bind(c, &readRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	err = model.MarkNotificationsRead(claims.ID, readRequest.UntilID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to mark notifications read.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.notifications_read"),
+	})
+}
+
+// 通知内容按类型及请求的语言生成，未知类型时返回保存的内容
+func notificationContent(c echo.Context, notification model.Notification) string {
+	switch notification.Type {
+	case model.NotificationPollClosed, model.NotificationPostApproved, model.NotificationPostRejected:
+		return Translate(c, "notification."+notification.Type)
+	}
+	return notification.Content
+}
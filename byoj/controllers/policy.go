@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/policy"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"strconv"
+
+	"github.com/labstack/echo"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 按内容过滤规则检查文本，命中拒绝规则时已写入响应并返回 false
+func checkPolicy(c echo.Context, texts ...string) (policy.Verdict, bool, error) {
+	verdict, err := checkContentPolicy(texts...)
+	if err != nil {
+		return verdict, false, ResponseRequestError(c, err)
+	}
+	return verdict, true, nil
+}
+
+// 按内容过滤规则检查文本，命中拒绝规则时返回 content_rejected
+func checkContentPolicy(texts ...string) (policy.Verdict, error) {
+	verdict, err := policy.Check(texts...)
+	if err != nil {
+		return verdict, internalRequestError("Check content policy failed.", err)
+	}
+	if verdict.Action == policy.ActionReject {
+		logs.Info("Content rejected by filter rules.", zap.Any("ruleIDs", verdict.RuleIDs))
+		return verdict, newRequestError(ErrContentRejected, nil)
+	}
+	return verdict, nil
+}
+
+// 规则编译失败时的字段错误，正则表达式的语法错误原样附在文本中
+func patternError(field string, err error) error {
+	switch err {
+	case policy.ErrEmptyPattern:
+		return i18n.NewError("param.empty", field)
+	case policy.ErrPatternTooLong:
+		return i18n.NewError("param.max_length", field, policy.MaxPatternLength)
+	}
+	return i18n.NewError("param.invalid_regex", field, err.Error())
+}
+
+// 资料无法进入审核流程，命中待审核规则同样视为拒绝，标记敏感的规则对资料无效
+func checkProfilePolicy(texts ...string) error {
+	verdict, err := checkContentPolicy(texts...)
+	if err != nil {
+		return err
+	}
+	if verdict.Action == policy.ActionHold {
+		logs.Info("Profile rejected by filter rules.", zap.Any("ruleIDs", verdict.RuleIDs))
+		return newRequestError(ErrContentRejected, nil)
+	}
+	return nil
+}
+
+/**
+ * 去掉命中当前用户屏蔽词的帖子，用户自己的帖子不受影响
+ * 在分页查询之后过滤，返回的帖子可能少于 limit 甚至为空，客户端应以 next_cursor 判断是否还有下一页
+ **/
+func hideMutedPosts(posts []model.Post, viewerID uint32) []model.Post {
+	if viewerID == 0 || len(posts) == 0 {
+		return posts
+	}
+	matcher, err := policy.MutedMatcher(viewerID)
+	if err != nil {
+		logs.Warn("Load muted words failed.", zap.Uint32("viewerID", viewerID), zap.Error(err))
+		return posts
+	}
+	if matcher == nil {
+		return posts
+	}
+	visible := make([]model.Post, 0, len(posts))
+	for _, post := range posts {
+		if post.AuthorID == viewerID || !matcher.Matches(post.Content) {
+			visible = append(visible, post)
+		}
+	}
+	return visible
+}
+
+type FilterRuleRequest struct {
+	RuleID  uint32 `json:"rule_id"`
+	Pattern string `json:"pattern"  validate:"max=200"`
+	IsRegex bool   `json:"is_regex"`
+	Action  string `json:"action"   validate:"omitempty,oneof=sensitive hold reject"`
+}
+
+type FilterRulesGetResponse struct {
+	RuleList []model.FilterRule `json:"rule_list"`
+}
+
+func AdminFilterPOST(c echo.Context) error {
+	logs.Debug("POST /admin/filter")
+
+	ruleRequest := FilterRuleRequest{}
+	_ok, err := Bind(c, &ruleRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	if !policy.ValidAction(ruleRequest.Action) {
+		return ResponseInvalidParameter(c, "action", "param.invalid")
+	}
+	_, err = policy.CompileRule(policy.Rule{Pattern: ruleRequest.Pattern, IsRegex: ruleRequest.IsRegex})
+	if err != nil {
+		return responseInvalidField(c, "pattern", patternError("pattern", err))
+	}
+
+	rule, err := model.CreateFilterRule(model.FilterRule{
+		CreatedBy: claims.ID,
+		Pattern:   ruleRequest.Pattern,
+		IsRegex:   ruleRequest.IsRegex,
+		Action:    ruleRequest.Action,
+	})
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to create filter rule.", err)
+	}
+	policy.Invalidate()
+
+	return ResponseOK(c, rule)
+}
+
+func AdminFiltersGET(c echo.Context) error {
+	logs.Debug("GET /admin/filter")
+
+	rules, err := model.GetFilterRules()
+	if err != nil {
+		return ResponseInternalServerError(c, "Get filter rules failed.", err)
+	}
+
+	return ResponseOK(c, FilterRulesGetResponse{
+		RuleList: rules,
+	})
+}
+
+func AdminFilterRemovePOST(c echo.Context) error {
+	logs.Debug("POST /admin/filter/remove")
+
+	ruleRequest := FilterRuleRequest{}
+	_ok, err := Bind(c, &ruleRequest)
+	if !_ok {
+		return err
+	}
+
+	err = model.DeleteFilterRule(ruleRequest.RuleID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrFilterRuleNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to delete filter rule.", err)
+	}
+	policy.Invalidate()
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.filter_remove"),
+	})
+}
+
+type HeldPostReviewRequest struct {
+	PostID  uint32 `json:"post_id" validate:"required"`
+	Approve bool   `json:"approve"`
+}
+
+type HeldPostsGetResponse struct {
+	PostList   []PostResponse `json:"post_list"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+func AdminHeldGET(c echo.Context) error {
+	logs.Debug("GET /admin/held")
+
+	var afterID uint64
+	var err error
+	if s := c.QueryParam("cursor"); s != "" {
+		afterID, err = strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return ResponseInvalidParameter(c, "cursor", "param.invalid")
+		}
+	}
+	limit := 20
+	if s := c.QueryParam("limit"); s != "" {
+		limit, err = strconv.Atoi(s)
+		if err != nil || limit <= 0 || limit > 100 {
+			return ResponseInvalidParameter(c, "limit", "param.invalid")
+		}
+	}
+
+	posts, err := model.GetHeldPosts(uint32(afterID), limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get held posts failed.", err)
+	}
+
+	response := HeldPostsGetResponse{
+		PostList: buildPostResponses(posts, nil, 0),
+	}
+	if len(posts) == limit {
+		response.NextCursor = strconv.FormatUint(uint64(posts[len(posts)-1].ID), 10)
+	}
+	return ResponseOK(c, response)
+}
+
+func AdminHeldReviewPOST(c echo.Context) error {
+	logs.Debug("POST /admin/held/review")
+
+	reviewRequest := HeldPostReviewRequest{}
+	_ok, err := Bind(c, &reviewRequest)
+	if !_ok {
+		return err
+	}
+
+	err = model.ReviewHeldPost(reviewRequest.PostID, reviewRequest.Approve)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrHeldPostNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to review post.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.post_review"),
+	})
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+const (
+	pollMinDuration = 5 * time.Minute
+	pollMaxDuration = 7 * 24 * time.Hour
+)
+
+type PollCreateRequest struct {
+	Options []string `json:"options"  validate:"min=2,max=4,dive,max=50"`
+	EndTime int64    `json:"end_time" validate:"required"`
+}
+
+type PollOptionResponse struct {
+	OptionID uint32 `json:"option_id"`
+	Text     string `json:"text"`
+	Votes    *int64 `json:"votes,omitempty"`
+}
+
+// 投票结束或当前用户已投票前不返回计票结果
+type PollResponse struct {
+	PollID        uint32               `json:"poll_id"`
+	EndTime       int64                `json:"end_time"`
+	Ended         bool                 `json:"ended"`
+	Voted         bool                 `json:"voted"`
+	VotedOptionID uint32               `json:"voted_option_id,omitempty"`
+	TotalVotes    *int64               `json:"total_votes,omitempty"`
+	Options       []PollOptionResponse `json:"options"`
+}
+
+type PollVoteRequest struct {
+	PostID   uint32 `json:"post_id"   validate:"required"`
+	OptionID uint32 `json:"option_id" validate:"required"`
+}
+
+func newPoll(request *PollCreateRequest, now time.Time) (*model.Poll, error) {
+	if request == nil {
+		return nil, nil
+	}
+	endTime := time.Unix(request.EndTime, 0)
+	if endTime.Before(now.Add(pollMinDuration)) || endTime.After(now.Add(pollMaxDuration)) {
+		return nil, i18n.NewError("poll.end_time")
+	}
+
+	poll := &model.Poll{EndTime: endTime}
+	seen := make(map[string]bool)
+	for _, text := range request.Options {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, i18n.NewError("poll.option_empty")
+		}
+		if seen[text] {
+			return nil, i18n.NewError("poll.option_duplicate")
+		}
+		seen[text] = true
+		poll.Options = append(poll.Options, model.PollOption{Text: text})
+	}
+	return poll, nil
+}
+
+func newPollResponse(poll model.Poll, tally model.PollTally, now time.Time) *PollResponse {
+	resp := &PollResponse{
+		PollID:        poll.ID,
+		EndTime:       poll.EndTime.Unix(),
+		Ended:         poll.IsEnded(now),
+		Voted:         tally.VotedOptionID != 0,
+		VotedOptionID: tally.VotedOptionID,
+		Options:       make([]PollOptionResponse, 0, len(poll.Options)),
+	}
+	visible := resp.Ended || resp.Voted
+	if visible {
+		total := tally.Total
+		resp.TotalVotes = &total
+	}
+	for _, option := range poll.Options {
+		optionResp := PollOptionResponse{
+			OptionID: option.ID,
+			Text:     option.Text,
+		}
+		if visible {
+			votes := tally.Votes[option.ID]
+			optionResp.Votes = &votes
+		}
+		resp.Options = append(resp.Options, optionResp)
+	}
+	return resp
+}
+
+func PostVotePOST(c echo.Context) error {
+	logs.Debug("POST /post/vote")
+
+	voteRequest := PollVoteRequest{}
+	_ok, err := Bind(c, &voteRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	post, err := model.FindPostByPostID(voteRequest.PostID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrPostNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Find post failed.", err)
+	}
+	if !post.IsPublic && post.AuthorID != claims.ID {
+		return ResponseError(c, ErrPostNotFound, gorm.ErrRecordNotFound)
+	}
+
+	poll, err := model.FindPollByPostID(post.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrPollNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Find poll failed.", err)
+	}
+
+	err = model.VotePoll(poll.ID, voteRequest.OptionID, claims.ID)
+	switch err {
+	case nil:
+	case model.ErrPollEnded:
+		return ResponseError(c, ErrPollEnded, err)
+	case model.ErrAlreadyVoted:
+		return ResponseError(c, ErrAlreadyVoted, err)
+	case model.ErrInvalidOption:
+		return ResponseInvalidParameter(c, "option_id", "param.invalid")
+	default:
+		return ResponseInternalServerError(c, "Failed to vote.", err)
+	}
+
+	polls, tallies, err := model.GetPollsByPostIDs([]uint32{post.ID}, claims.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get poll result failed.", err)
+	}
+	return ResponseOK(c, newPollResponse(polls[post.ID], tallies[poll.ID], time.Now()))
+}
@@ -3,25 +3,33 @@ package controllers
 import (
 	"byoj/controllers/auth"
 	"byoj/model"
+	"byoj/policy"
+	"byoj/utils/content"
+	"byoj/utils/i18n"
 	"byoj/utils/logs"
 	"time"
 
 	"github.com/labstack/echo"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
 type PostCreateRequest struct {
-	AuthorID    uint32 `json:"user_id"`
-	AuthorName  string `json:"user_name"`
-	AuthorEmail string `json:"email"`
-	Content     string `json:"content"`
+	AuthorID    uint32             `json:"user_id"`
+	AuthorName  string             `json:"user_name"`
+	AuthorEmail string             `json:"email"`
+	Content     string             `json:"content"`
+	MediaIDs    []uint32           `json:"media_ids"`
+	Poll        *PollCreateRequest `json:"poll"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
 }
 
 type PostCreateResponse struct {
 	Status   string `json:"status"`
 	PostID   uint32 `json:"post_id"`
 	IsPublic bool   `json:"is_public"`
+	Held     bool   `json:"held"`
 }
 
 func PostPOST(c echo.Context) error {
@@ -33,67 +41,176 @@ func PostPOST(c echo.Context) error {
 		return err
 	}
 
-	user, err, e500 := FindUser(c, model.User{
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	resp, err := CreatePost(Lang(c), claims, postRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+/**
+ * 发布帖子，REST、GraphQL 及 gRPC 共用；返回的状态文本按 lang 翻译
+ * 请求中的作者须与令牌中的用户一致，命中待审核规则的帖子不立即公开
+ **/
+func CreatePost(lang string, claims auth.Claims, postRequest PostCreateRequest) (PostCreateResponse, error) {
+	if err := validateFields(postRequest); err != nil {
+		return PostCreateResponse{}, err
+	}
+
+	user, err := findUser(model.User{
 		ID:       postRequest.AuthorID,
 		UserName: postRequest.AuthorName,
 		Email:    postRequest.AuthorEmail,
 	})
-	if e500 {
-		return err
-	}
 	if err != nil {
-		return ResponseBadRequest(c, "Find user failed.", err)
+		return PostCreateResponse{}, err
 	}
 
 	if user.Deleted {
-		return ResponseBadRequest(c, "This user has been deleted.", nil)
+		return PostCreateResponse{}, newRequestError(ErrUserDeleted, nil)
 	}
 
 	if !user.Verified {
-		return ResponseBadRequest(c, "This user has not been verified.", nil)
+		return PostCreateResponse{}, newRequestError(ErrUserNotVerified, nil)
 	}
 
-	claims, err := auth.GetClaimsFromHeader(c)
-	if err != nil {
-		return ResponseBadRequest(c, err.Error(), nil)
+	if user.IsSuspended(time.Now()) {
+		return PostCreateResponse{}, suspendedError(user)
 	}
+
 	if claims.ID != user.ID || claims.UserName != user.UserName {
-		return ResponseForbidden(c, "You cannot post other's post.", nil)
+		return PostCreateResponse{}, newRequestError(ErrForbidden, nil)
+	}
+
+	mediaIDs, err := uniqueMediaIDs(postRequest.MediaIDs)
+	if err != nil {
+		return PostCreateResponse{}, invalidFieldError("media_ids", err)
+	}
+
+	now := time.Now()
+	poll, err := newPoll(postRequest.Poll, now)
+	if err != nil {
+		return PostCreateResponse{}, invalidFieldError("poll", err)
+	}
+
+	postContent, rerr := content.Validate(postRequest.Content, len(mediaIDs) > 0 || poll != nil)
+	if rerr != nil {
+		return PostCreateResponse{}, contentInvalidError(rerr)
+	}
+
+	contentWarning, err := checkContentWarning(postRequest.ContentWarning)
+	if err != nil {
+		return PostCreateResponse{}, invalidFieldError("content_warning", err)
+	}
+
+	texts := []string{postContent, contentWarning}
+	if poll != nil {
+		for _, option := range poll.Options {
+			texts = append(texts, option.Text)
+		}
+	}
+	verdict, err := checkContentPolicy(texts...)
+	if err != nil {
+		return PostCreateResponse{}, err
 	}
 
-	post, err := model.CreatePost(user.ID, time.Now(), postRequest.Content, true)
+	post, err := model.PublishPost(model.Post{
+		AuthorID:  user.ID,
+		Time:      now,
+		Content:   postContent,
+		IsPublic:  true,
+		Held:      verdict.Action == policy.ActionHold,
+		Sensitive: verdict.Action == policy.ActionSensitive,
+
+		ContentWarning: contentWarning,
+		SensitiveMedia: postRequest.SensitiveMedia && len(mediaIDs) > 0,
+	}, mediaIDs, poll)
+	if err == model.ErrInvalidAttachment {
+		return PostCreateResponse{}, invalidParameterError("media_ids", "param.invalid")
+	}
 	if err != nil {
-		return ResponseInternalServerError(c, "Failed to create post into database.", err)
+		return PostCreateResponse{}, internalRequestError("Failed to create post into database.", err)
 	}
 
-	return ResponseOK(c, PostCreateResponse{
-		Status:   "Create post successfully.",
+	status := i18n.Translate(lang, "status.post_create")
+	if post.Held {
+		status = i18n.Translate(lang, "status.post_held")
+	}
+	return PostCreateResponse{
+		Status:   status,
 		PostID:   post.ID,
 		IsPublic: post.IsPublic,
-	})
+		Held:     post.Held,
+	}, nil
+}
+
+func uniqueMediaIDs(ids []uint32) ([]uint32, error) {
+	mediaIDs := make([]uint32, 0, len(ids))
+	seen := make(map[uint32]bool)
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			mediaIDs = append(mediaIDs, id)
+		}
+	}
+	if len(mediaIDs) > model.PostAttachmentLimit {
+		return nil, i18n.NewError("param.max_items", "media_ids", model.PostAttachmentLimit)
+	}
+	return mediaIDs, nil
 }
 
 type PostGetRequest struct {
-	AuthorID    uint32 `json:"user_id"`
-	AuthorName  string `json:"user_name"`
-	AuthorEmail string `json:"email"`
-	Limit       int    `json:"limit"`
-	OrderBy     string `json:"order_by"`
-	StartTime   int64  `json:"start_time"`
+	AuthorID    uint32 `json:"user_id"    query:"user_id"`
+	AuthorName  string `json:"user_name"  query:"user_name"`
+	AuthorEmail string `json:"email"      query:"email"`
+	Limit       int    `json:"limit"      query:"limit"      validate:"gte=0,lte=100"`
+	OrderBy     string `json:"order_by"   query:"order_by"   validate:"omitempty,oneof=time random"`
+	StartTime   int64  `json:"start_time" query:"start_time"`
+	// 上一页的 next_cursor，仅按时间排序时有效
+	Cursor string `json:"cursor" query:"cursor"`
 }
 
 type PostResponse struct {
-	AuthorID    uint32 `json:"user_id"`
-	AuthorName  string `json:"user_name"`
-	AuthorEmail string `json:"email"`
-	PostID      uint32 `json:"post_id"`
-	Time        int64  `json:"time"`
-	Content     string `json:"content"`
-	IsPublic    bool   `json:"is_public"`
+	AuthorID    uint32           `json:"user_id"`
+	AuthorName  string           `json:"user_name"`
+	AuthorEmail string           `json:"email"`
+	PostID      uint32           `json:"post_id"`
+	Time        int64            `json:"time"`
+	Content     string           `json:"content"`
+	Text        string           `json:"text"`
+	Entities    []content.Entity `json:"entities"`
+	HTML        string           `json:"html"`
+	IsPublic    bool             `json:"is_public"`
+	Sensitive   bool             `json:"sensitive"`
+	Collapsed   bool             `json:"collapsed"`
+	Bookmarked  bool             `json:"bookmarked"`
+	Liked       bool             `json:"liked"`
+	LikeCount   int64            `json:"like_count"`
+	MediaList   []MediaResponse  `json:"media_list"`
+	Poll        *PollResponse    `json:"poll,omitempty"`
+	Card        *CardResponse    `json:"card,omitempty"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+type CardResponse struct {
+	URL         string `json:"url"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	SiteName    string `json:"site_name"`
 }
 
+// 按时间排序且可能还有更多帖子时 NextCursor 不为空，屏蔽词过滤后的 PostList 可能不足一页
 type PostGetResponse struct {
-	PostList []PostResponse `json:"post_list"`
+	PostList   []PostResponse `json:"post_list"`
+	NextCursor string         `json:"next_cursor"`
 }
 
 func PostGET(c echo.Context) error {
@@ -105,18 +222,40 @@ func PostGET(c echo.Context) error {
 		return err
 	}
 
-	user, err, e500 := FindUser(c, model.User{
-		ID:       postRequest.AuthorID,
-		UserName: postRequest.AuthorName,
-		Email:    postRequest.AuthorEmail,
-	})
-	if e500 {
-		return err
+	resp, err := GetPosts(GetViewerID(c), postRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 查询公开帖子，可按作者筛选，REST 与 gRPC 共用；viewerID 为 0 表示未登录
+func GetPosts(viewerID uint32, postRequest PostGetRequest) (PostGetResponse, error) {
+	if err := validateFields(postRequest); err != nil {
+		return PostGetResponse{}, err
+	}
+
+	// 未指定用户时不按发布者筛选
+	var user model.User
+	if postRequest.AuthorID != 0 || postRequest.AuthorName != "" || postRequest.AuthorEmail != "" {
+		var err error
+		user, err = findUser(model.User{
+			ID:       postRequest.AuthorID,
+			UserName: postRequest.AuthorName,
+			Email:    postRequest.AuthorEmail,
+		})
+		if err != nil {
+			return PostGetResponse{}, err
+		}
+	}
+
+	cursor, err := model.DecodePostCursor(postRequest.Cursor)
+	if err != nil {
+		return PostGetResponse{}, invalidParameterError("cursor", "param.invalid")
 	}
-	if err == gorm.ErrRecordNotFound {
-		return ResponseBadRequest(c, "User not found.", err)
-	} else {
-		user.ID = 0
+	limit := postRequest.Limit
+	if limit == 0 {
+		limit = 20
 	}
 
 	mp := make(map[uint32]model.User)
@@ -124,31 +263,149 @@ func PostGET(c echo.Context) error {
 		mp[user.ID] = user
 	}
 
-	posts, err := model.GetPostsList(user.ID, time.Unix(postRequest.StartTime, 0), false, postRequest.OrderBy, postRequest.Limit)
+	posts, err := model.GetPostsList(user.ID, time.Unix(postRequest.StartTime, 0), false, postRequest.OrderBy, cursor, limit)
 	if err != nil {
-		return ResponseInternalServerError(c, "Get posts list failed.", err)
+		return PostGetResponse{}, internalRequestError("Get posts list failed.", err)
 	}
 
+	return newPostGetResponse(posts, mp, viewerID, postRequest.OrderBy, limit), nil
+}
+
+// 按时间排序时以过滤前的最后一个帖子作为下一页的游标
+func newPostGetResponse(posts []model.Post, authors map[uint32]model.User, viewerID uint32, orderBy string, limit int) PostGetResponse {
 	resp := PostGetResponse{
-		PostList: make([]PostResponse, 0),
+		PostList: buildPostResponses(posts, authors, viewerID),
+	}
+	if orderBy == "time" && len(posts) == limit {
+		resp.NextCursor = model.NewPostCursor(posts[len(posts)-1]).Encode()
+	}
+	return resp
+}
+
+// 构造帖子响应，内容与 REST 接口一致，供 gRPC 推送等不经过 REST 接口的场景使用
+func NewPostResponses(posts []model.Post, viewerID uint32) []PostResponse {
+	return buildPostResponses(posts, nil, viewerID)
+}
+
+// authors 为已查询到的作者缓存，可为 nil；viewerID 为 0 时不查询收藏状态，也不过滤屏蔽词
+func buildPostResponses(posts []model.Post, authors map[uint32]model.User, viewerID uint32) []PostResponse {
+	if authors == nil {
+		authors = make(map[uint32]model.User)
+	}
+	posts = hideMutedPosts(posts, viewerID)
+	// 未登录时默认折叠
+	collapseSensitive := true
+	if viewerID != 0 {
+		viewer, err := model.FindUserByID(viewerID)
+		if err != nil {
+			logs.Warn("Find viewer failed.", zap.Uint32("viewerID", viewerID), zap.Error(err))
+		} else {
+			collapseSensitive = viewer.CollapseSensitive
+		}
 	}
+	var err error
+	postIDs := make([]uint32, 0, len(posts))
 	for _, post := range posts {
-		if mp[post.AuthorID].ID == 0 {
-			mp[post.AuthorID], err = model.FindUserByID(post.AuthorID)
+		postIDs = append(postIDs, post.ID)
+	}
+	bookmarked := make(map[uint32]bool)
+	liked := make(map[uint32]bool)
+	if viewerID != 0 && len(posts) > 0 {
+		bookmarked, err = model.GetBookmarkedPostIDs(viewerID, postIDs)
+		if err != nil {
+			logs.Warn("Find bookmarked posts failed.", zap.Uint32("viewerID", viewerID), zap.Error(err))
+		}
+		liked, err = model.GetLikedPostIDs(viewerID, postIDs)
+		if err != nil {
+			logs.Warn("Find liked posts failed.", zap.Uint32("viewerID", viewerID), zap.Error(err))
+		}
+	}
+	attachments := make(map[uint32][]model.Attachment)
+	polls := make(map[uint32]model.Poll)
+	tallies := make(map[uint32]model.PollTally)
+	likeCounts := make(map[uint32]int64)
+	if len(posts) > 0 {
+		// 作者一次查询，避免每个帖子单独查询
+		var missing []uint32
+		for _, post := range posts {
+			if authors[post.AuthorID].ID == 0 {
+				missing = append(missing, post.AuthorID)
+			}
+		}
+		if len(missing) > 0 {
+			found, err := model.FindUsersByIDs(missing)
 			if err != nil {
-				logs.Warn("Find user for post failed.", zap.Uint32("AuthorID", post.AuthorID), zap.Error(err))
+				logs.Warn("Find users for posts failed.", zap.Error(err))
 			}
+			for id, user := range found {
+				authors[id] = user
+			}
+		}
+		likeCounts, err = model.GetLikeCounts(postIDs)
+		if err != nil {
+			logs.Warn("Count likes for posts failed.", zap.Error(err))
+		}
+		attachments, err = model.GetAttachmentsByPostIDs(postIDs)
+		if err != nil {
+			logs.Warn("Find attachments for posts failed.", zap.Error(err))
+		}
+		polls, tallies, err = model.GetPollsByPostIDs(postIDs, viewerID)
+		if err != nil {
+			logs.Warn("Find polls for posts failed.", zap.Error(err))
+		}
+	}
+	previewURLs := make(map[uint32]string)
+	urls := make([]string, 0, len(posts))
+	for _, post := range posts {
+		if u := model.PreviewURL(post.Content); u != "" {
+			previewURLs[post.ID] = u
+			urls = append(urls, u)
 		}
-		resp.PostList = append(resp.PostList, PostResponse{
+	}
+	previews, err := model.GetLinkPreviewsByURLs(urls)
+	if err != nil {
+		logs.Warn("Find link previews for posts failed.", zap.Error(err))
+	}
+	now := time.Now()
+	list := make([]PostResponse, 0, len(posts))
+	for _, post := range posts {
+		rendered := content.Render(post.Content)
+		list = append(list, PostResponse{
 			AuthorID:    post.AuthorID,
-			AuthorName:  mp[post.AuthorID].UserName,
-			AuthorEmail: mp[post.AuthorID].Email,
+			AuthorName:  authors[post.AuthorID].UserName,
+			AuthorEmail: authors[post.AuthorID].Email,
 			PostID:      post.ID,
 			Time:        post.Time.Unix(),
 			Content:     post.Content,
+			Text:        rendered.Text,
+			Entities:    rendered.Entities,
+			HTML:        rendered.HTML,
 			IsPublic:    post.IsPublic,
+			Sensitive:   post.Sensitive,
+			Collapsed:   isCollapsed(post, collapseSensitive),
+			Bookmarked:  bookmarked[post.ID],
+			Liked:       liked[post.ID],
+			LikeCount:   likeCounts[post.ID],
+			MediaList:   make([]MediaResponse, 0, len(attachments[post.ID])),
+
+			ContentWarning: post.ContentWarning,
+			SensitiveMedia: post.SensitiveMedia,
 		})
+		for _, attachment := range attachments[post.ID] {
+			list[len(list)-1].MediaList = append(list[len(list)-1].MediaList, newMediaResponse(attachment))
+		}
+		if poll, ok := polls[post.ID]; ok {
+			list[len(list)-1].Poll = newPollResponse(poll, tallies[poll.ID], now)
+		}
+		if p, ok := previews[previewURLs[post.ID]]; ok {
+			list[len(list)-1].Card = &CardResponse{
+				URL:         p.URL,
+				Title:       p.Title,
+				Description: p.Description,
+				ImageURL:    p.ImageURL,
+				SiteName:    p.SiteName,
+			}
+		}
 	}
-
-	return ResponseOK(c, resp)
+	return list
 }
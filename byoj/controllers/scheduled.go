@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/policy"
+	"byoj/utils/content"
+	"byoj/utils/logs"
+	"time"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+const scheduleMaxAhead = 365 * 24 * time.Hour
+
+type ScheduledPostRequest struct {
+	ScheduledID uint32             `json:"scheduled_id"`
+	Content     string             `json:"content"`
+	MediaIDs    []uint32           `json:"media_ids"`
+	Poll        *PollCreateRequest `json:"poll"`
+	ScheduledAt int64              `json:"scheduled_at"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+type ScheduledPostResponse struct {
+	ScheduledID     uint32   `json:"scheduled_id"`
+	Content         string   `json:"content"`
+	MediaIDs        []uint32 `json:"media_ids"`
+	PollOptions     []string `json:"poll_options"`
+	PollEndTime     int64    `json:"poll_end_time"`
+	ScheduledAt     int64    `json:"scheduled_at"`
+	Status          string   `json:"status"`
+	PublishedPostID uint32   `json:"published_post_id"`
+	FailReason      string   `json:"fail_reason"`
+
+	ContentWarning string `json:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media"`
+}
+
+type ScheduledPostGetResponse struct {
+	ScheduledList []ScheduledPostResponse `json:"scheduled_list"`
+}
+
+func newScheduledPostResponse(scheduled model.ScheduledPost) ScheduledPostResponse {
+	resp := ScheduledPostResponse{
+		ScheduledID:     scheduled.ID,
+		Content:         scheduled.Content,
+		MediaIDs:        scheduled.MediaIDs,
+		PollOptions:     scheduled.PollOptions,
+		Status:          scheduled.Status,
+		PublishedPostID: scheduled.PublishedPostID,
+		FailReason:      scheduled.FailReason,
+
+		ContentWarning: scheduled.ContentWarning,
+		SensitiveMedia: scheduled.SensitiveMedia,
+	}
+	if resp.MediaIDs == nil {
+		resp.MediaIDs = make([]uint32, 0)
+	}
+	if resp.PollOptions == nil {
+		resp.PollOptions = make([]string, 0)
+	}
+	if len(scheduled.PollOptions) > 0 {
+		resp.PollEndTime = scheduled.PollEndTime.Unix()
+	}
+	if scheduled.ScheduledAt != nil {
+		resp.ScheduledAt = scheduled.ScheduledAt.Unix()
+	}
+	return resp
+}
+
+/**
+ * 校验请求并转换为 ScheduledPost
+ * scheduled_at 为 0 时保存为草稿，早于当前时间时在下一次任务执行时发布
+ * 投票的结束时间相对于发布时间校验
+ **/
+func bindScheduledPost(c echo.Context) (scheduled model.ScheduledPost, ok bool, err error) {
+	scheduledRequest := ScheduledPostRequest{}
+	_ok, err := Bind(c, &scheduledRequest)
+	if !_ok {
+		return scheduled, false, err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return scheduled, false, ResponseError(c, ErrUnauthorized, err)
+	}
+	user, err := model.FindUserByID(claims.ID)
+	if err != nil {
+		return scheduled, false, ResponseInternalServerError(c, "Find user failed.", err)
+	}
+	if user.Deleted {
+		return scheduled, false, ResponseError(c, ErrUserDeleted, nil)
+	}
+	if !user.Verified {
+		return scheduled, false, ResponseError(c, ErrUserNotVerified, nil)
+	}
+	now := time.Now()
+	if user.IsSuspended(now) {
+		return scheduled, false, ResponseSuspended(c, user)
+	}
+
+	scheduled = model.ScheduledPost{
+		ID:       scheduledRequest.ScheduledID,
+		AuthorID: user.ID,
+		Content:  scheduledRequest.Content,
+		IsPublic: true,
+	}
+
+	publishAt := now
+	if scheduledRequest.ScheduledAt != 0 {
+		t := time.Unix(scheduledRequest.ScheduledAt, 0)
+		if t.After(now.Add(scheduleMaxAhead)) {
+			return scheduled, false, ResponseInvalidParameter(c, "scheduled_at", "scheduled.too_far")
+		}
+		if t.After(now) {
+			publishAt = t
+		}
+		scheduled.ScheduledAt = &t
+	}
+
+	scheduled.MediaIDs, err = uniqueMediaIDs(scheduledRequest.MediaIDs)
+	if err != nil {
+		return scheduled, false, responseInvalidField(c, "media_ids", err)
+	}
+	available, err := model.CheckAttachmentsAvailable(user.ID, scheduled.MediaIDs)
+	if err != nil {
+		return scheduled, false, ResponseInternalServerError(c, "Check media failed.", err)
+	}
+	if !available {
+		return scheduled, false, ResponseInvalidParameter(c, "media_ids", "param.invalid")
+	}
+
+	poll, err := newPoll(scheduledRequest.Poll, publishAt)
+	if err != nil {
+		return scheduled, false, responseInvalidField(c, "poll", err)
+	}
+	postContent, rerr := content.Validate(scheduledRequest.Content, len(scheduled.MediaIDs) > 0 || poll != nil)
+	if rerr != nil {
+		return scheduled, false, ResponseContentInvalid(c, rerr)
+	}
+	scheduled.Content = postContent
+
+	scheduled.ContentWarning, err = checkContentWarning(scheduledRequest.ContentWarning)
+	if err != nil {
+		return scheduled, false, responseInvalidField(c, "content_warning", err)
+	}
+	scheduled.SensitiveMedia = scheduledRequest.SensitiveMedia && len(scheduled.MediaIDs) > 0
+
+	if poll != nil {
+		scheduled.PollEndTime = poll.EndTime
+		for _, option := range poll.Options {
+			scheduled.PollOptions = append(scheduled.PollOptions, option.Text)
+		}
+	}
+
+	verdict, ok, err := checkPolicy(c, append([]string{scheduled.Content, scheduled.ContentWarning}, scheduled.PollOptions...)...)
+	if !ok {
+		return scheduled, false, err
+	}
+	scheduled.Held = verdict.Action == policy.ActionHold
+	scheduled.Sensitive = verdict.Action == policy.ActionSensitive
+
+	return scheduled, true, nil
+}
+
+func PostScheduledPOST(c echo.Context) error {
+	logs.Debug("POST /post/scheduled")
+
+	scheduled, ok, err := bindScheduledPost(c)
+	if !ok {
+		return err
+	}
+
+	scheduled.ID = 0
+	scheduled, err = model.CreateScheduledPost(scheduled)
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to create scheduled post into database.", err)
+	}
+
+	return ResponseOK(c, newScheduledPostResponse(scheduled))
+}
+
+func PostScheduledGET(c echo.Context) error {
+	logs.Debug("GET /post/scheduled")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	list, err := model.GetScheduledPosts(claims.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Get scheduled posts failed.", err)
+	}
+
+	resp := ScheduledPostGetResponse{
+		ScheduledList: make([]ScheduledPostResponse, 0, len(list)),
+	}
+	for _, scheduled := range list {
+		resp.ScheduledList = append(resp.ScheduledList, newScheduledPostResponse(scheduled))
+	}
+	return ResponseOK(c, resp)
+}
+
+func PostScheduledEditPOST(c echo.Context) error {
+	logs.Debug("POST /post/scheduled/edit")
+
+	scheduled, ok, err := bindScheduledPost(c)
+	if !ok {
+		return err
+	}
+
+	scheduled, err = model.UpdateScheduledPost(scheduled)
+	if err == model.ErrScheduledPostNotEditable {
+		return ResponseError(c, ErrScheduledNotFound, err)
+	}
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to update scheduled post.", err)
+	}
+
+	return ResponseOK(c, newScheduledPostResponse(scheduled))
+}
+
+func PostScheduledCancelPOST(c echo.Context) error {
+	logs.Debug("POST /post/scheduled/cancel")
+
+	scheduledRequest := ScheduledPostRequest{}
+	_ok, err := Bind(c, &scheduledRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	err = model.DeleteScheduledPost(scheduledRequest.ScheduledID, claims.ID)
+	if err == model.ErrScheduledPostNotEditable || err == gorm.ErrRecordNotFound {
+		return ResponseError(c, ErrScheduledNotFound, err)
+	}
+	if err != nil {
+		return ResponseInternalServerError(c, "Failed to cancel scheduled post.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.scheduled_cancel"),
+	})
+}
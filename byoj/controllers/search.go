@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"byoj/model"
+	"byoj/utils/logs"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	searchTypePost  = "post"
+	searchTypeUser  = "user"
+	searchMaxLimit  = 100
+	searchMaxLength = 256
+)
+
+type SearchResponse struct {
+	PostList   []PostResponse    `json:"post_list"`
+	UserList   []UserGETResponse `json:"user_list"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+func SearchGET(c echo.Context) error {
+	logs.Debug("GET /search")
+
+	q := strings.TrimSpace(c.QueryParam("q"))
+	if q == "" {
+		return ResponseInvalidParameter(c, "q", "param.empty")
+	}
+	if len(q) > searchMaxLength {
+		return ResponseInvalidParameter(c, "q", "param.max_length", searchMaxLength)
+	}
+	query := model.ParseSearchQuery(q)
+	if query.IsEmpty() {
+		return ResponseInvalidParameter(c, "q", "param.empty")
+	}
+
+	cursor, err := model.DecodeSearchCursor(c.QueryParam("cursor"))
+	if err != nil {
+		return ResponseInvalidParameter(c, "cursor", "param.invalid")
+	}
+
+	limit := 20
+	if l := c.QueryParam("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil || limit <= 0 || limit > searchMaxLimit {
+			return ResponseInvalidParameter(c, "limit", "param.invalid")
+		}
+	}
+
+	switch c.QueryParam("type") {
+	case "", searchTypePost:
+		return searchPosts(c, query, cursor, limit)
+	case searchTypeUser:
+		return searchUsers(c, query, cursor, limit)
+	default:
+		return ResponseInvalidParameter(c, "type", "param.invalid")
+	}
+}
+
+func searchPosts(c echo.Context, query model.SearchQuery, cursor *model.SearchCursor, limit int) error {
+	viewerID := GetViewerID(c)
+	results, err := model.SearchPosts(query, viewerID, cursor, limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Search posts failed.", err)
+	}
+
+	posts := make([]model.Post, 0, len(results))
+	for _, result := range results {
+		posts = append(posts, result.Post)
+	}
+	resp := SearchResponse{
+		PostList: buildPostResponses(posts, nil, viewerID),
+	}
+	if len(results) == limit {
+		last := results[len(results)-1]
+		resp.NextCursor = model.SearchCursor{Rank: last.Rank, ID: last.ID}.Encode()
+	}
+	return ResponseOK(c, resp)
+}
+
+func searchUsers(c echo.Context, query model.SearchQuery, cursor *model.SearchCursor, limit int) error {
+	results, err := model.SearchUsers(query, cursor, limit)
+	if err != nil {
+		return ResponseInternalServerError(c, "Search users failed.", err)
+	}
+
+	resp := SearchResponse{
+		UserList: make([]UserGETResponse, 0, len(results)),
+	}
+	for _, result := range results {
+		resp.UserList = append(resp.UserList, newUserGETResponse(result.User))
+	}
+	if len(results) == limit {
+		last := results[len(results)-1]
+		resp.NextCursor = model.SearchCursor{Rank: last.Rank, ID: last.ID}.Encode()
+	}
+	return ResponseOK(c, resp)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/utils/content"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"strings"
+
+	"github.com/labstack/echo"
+	"gorm.io/gorm"
+)
+
+// 内容警告按字素簇计算的长度上限
+const maxContentWarningLength = 100
+
+// 规范化内容警告，内容警告只允许单行
+func checkContentWarning(s string) (string, error) {
+	s = content.Normalize(s)
+	if strings.ContainsAny(s, "\n\t") {
+		return s, i18n.NewError("param.single_line", "content_warning")
+	}
+	if content.Length(s) > maxContentWarningLength {
+		return s, i18n.NewError("param.max_length", "content_warning", maxContentWarningLength)
+	}
+	return s, nil
+}
+
+// 帖子带有内容警告或任一敏感标记，且查看者选择折叠时，客户端应折叠显示
+func isCollapsed(post model.Post, collapseSensitive bool) bool {
+	return collapseSensitive && (post.ContentWarning != "" || post.Sensitive || post.SensitiveMedia)
+}
+
+// 只修改请求中提供的字段，未提供的保持不变
+type PostSensitivityRequest struct {
+	PostID         uint32  `json:"post_id" validate:"required"`
+	Sensitive      *bool   `json:"sensitive"`
+	SensitiveMedia *bool   `json:"sensitive_media"`
+	ContentWarning *string `json:"content_warning"`
+}
+
+func AdminPostSensitivePOST(c echo.Context) error {
+	logs.Debug("POST /admin/post/sensitive")
+
+	sensitivityRequest := PostSensitivityRequest{}
+	_ok, err := Bind(c, &sensitivityRequest)
+	if !_ok {
+		return err
+	}
+
+	if sensitivityRequest.Sensitive == nil && sensitivityRequest.SensitiveMedia == nil && sensitivityRequest.ContentWarning == nil {
+		return ResponseInvalidParameter(c, "sensitive", "param.required")
+	}
+	if sensitivityRequest.ContentWarning != nil {
+		contentWarning, err := checkContentWarning(*sensitivityRequest.ContentWarning)
+		if err != nil {
+			return responseInvalidField(c, "content_warning", err)
+		}
+		sensitivityRequest.ContentWarning = &contentWarning
+	}
+
+	err = model.UpdatePostSensitivity(sensitivityRequest.PostID, sensitivityRequest.Sensitive,
+		sensitivityRequest.SensitiveMedia, sensitivityRequest.ContentWarning)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrPostNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to update post.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.post_update"),
+	})
+}
+
+type UserSettings struct {
+	CollapseSensitive bool `json:"collapse_sensitive"`
+}
+
+// 只修改请求中提供的设置
+type UserSettingsRequest struct {
+	CollapseSensitive *bool `json:"collapse_sensitive"`
+}
+
+func UserSettingsGET(c echo.Context) error {
+	logs.Debug("GET /user/settings")
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	user, err := model.FindUserByID(claims.ID)
+	if err != nil {
+		return ResponseInternalServerError(c, "Find user failed.", err)
+	}
+
+	return ResponseOK(c, UserSettings{
+		CollapseSensitive: user.CollapseSensitive,
+	})
+}
+
+func UserSettingsPOST(c echo.Context) error {
+	logs.Debug("POST /user/settings")
+
+	settingsRequest := UserSettingsRequest{}
+	_ok, err := Bind(c, &settingsRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	user, err := model.UpdateUserSettings(claims.ID, settingsRequest.CollapseSensitive)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrUserNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to update settings.", err)
+	}
+
+	return ResponseOK(c, UserSettings{
+		CollapseSensitive: user.CollapseSensitive,
+	})
+}
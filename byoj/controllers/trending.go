@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"byoj/model"
+	"byoj/tasks"
+	"byoj/utils/logs"
+
+	"github.com/labstack/echo"
+)
+
+const defaultTrendingWindow = "24h"
+
+type TrendingGetResponse struct {
+	Window      string               `json:"window"`
+	HashtagList []model.HashtagScore `json:"hashtag_list"`
+	PostList    []PostResponse       `json:"post_list"`
+	UpdatedAt   int64                `json:"updated_at"`
+}
+
+func TrendingGET(c echo.Context) error {
+	logs.Debug("GET /trending")
+
+	window := c.QueryParam("window")
+	if window == "" {
+		window = defaultTrendingWindow
+	}
+	valid := false
+	for _, w := range tasks.TrendingWindows {
+		if w.Name == window {
+			valid = true
+		}
+	}
+	if !valid {
+		return ResponseInvalidParameter(c, "window", "param.invalid")
+	}
+
+	trending, err := tasks.GetTrending(window)
+	if err != nil {
+		return ResponseError(c, ErrServiceUnavailable, err)
+	}
+
+	hashtags := trending.Hashtags
+	if hashtags == nil {
+		hashtags = make([]model.HashtagScore, 0)
+	}
+	return ResponseOK(c, TrendingGetResponse{
+		Window:      window,
+		HashtagList: hashtags,
+		PostList:    buildPostResponses(trending.Posts, nil, GetViewerID(c)),
+		UpdatedAt:   trending.UpdatedAt.Unix(),
+	})
+}
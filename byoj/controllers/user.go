@@ -3,41 +3,36 @@ package controllers
 import (
 	"byoj/controllers/auth"
 	"byoj/model"
+	"byoj/utils/i18n"
 	"byoj/utils/logs"
-	"errors"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo"
 	"gorm.io/gorm"
 )
 
-func checkUserName(userName string) error {
-	if userName == "" {
-		return errors.New("Empty user_name.")
-	}
-
+// 用户名或邮箱已被注册时返回对应的字段错误
+func checkUserTaken(userName string, email string) []FieldError {
+	var details []FieldError
 	result, _ := model.FindUserByName(userName)
 	if result.UserName == userName {
-		return errors.New("Username have been used.")
-	}
-
-	return nil
-}
-
-func checkUserEmail(email string) error {
-	if email == "" {
-		return errors.New("Empty email.")
+		details = append(details, newFieldError("user_name", "unique", "param.taken"))
 	}
-
-	result, _ := model.FindUserByEmail(email)
+	result, _ = model.FindUserByEmail(email)
 	if result.Email == email {
-		return errors.New("Email have been used.")
+		details = append(details, newFieldError("email", "unique", "param.taken"))
 	}
-
-	return nil
+	return details
 }
 
-func FindUser(c echo.Context, request model.User) (user model.User, err error, isInternalServerError bool) {
-	user = request
+/**
+ * 按 user_id、email、user_name 的顺序查找用户
+ * 未找到时返回 user_not_found，三者均为空时返回 invalid_parameter
+ **/
+func findUser(request model.User) (model.User, error) {
+	var user model.User
+	var err error
 	if request.ID != 0 {
 		user, err = model.FindUserByID(request.ID)
 	} else if request.Email != "" {
@@ -45,44 +40,63 @@ func FindUser(c echo.Context, request model.User) (user model.User, err error, i
 	} else if request.UserName != "" {
 		user, err = model.FindUserByName(request.UserName)
 	} else {
-		return user, errors.New("Author ID, email or username is required."), false
+		return request, invalidFieldError("user_id", i18n.NewError("param.user_required"))
 	}
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return user, err, false
+			return user, newRequestError(ErrUserNotFound, err)
 		}
-		return user, ResponseInternalServerError(c, "Find user failed", err), true
+		return user, internalRequestError("Find user failed", err)
 	}
-	return user, nil, false
+	return user, nil
+}
+
+type UserRegisterRequest struct {
+	UserName    string `json:"user_name" validate:"required,max=32"`
+	Email       string `json:"email"     validate:"required,email"`
+	PasswordMD5 string `json:"password"  validate:"required"`
+	RealName    string `json:"real_name" validate:"max=50"`
+	Bio         string `json:"bio"       validate:"max=160"`
 }
 
 func UserRegisterPOST(c echo.Context) error {
 	logs.Debug("POST /user/register")
 
-	user := model.User{}
+	user := UserRegisterRequest{}
 	_ok, err := Bind(c, &user)
 	if !_ok {
 		return err
 	}
 
-	err = checkUserName(user.UserName)
+	resp, err := RegisterUser(Lang(c), user)
 	if err != nil {
-		return ResponseBadRequest(c, err.Error(), nil)
+		return ResponseRequestError(c, err)
 	}
+	return ResponseOK(c, resp)
+}
 
-	err = checkUserEmail(user.Email)
-	if err != nil {
-		return ResponseBadRequest(c, err.Error(), nil)
+// 注册用户，REST、GraphQL 及 gRPC 共用；返回的状态文本按 lang 翻译
+func RegisterUser(lang string, user UserRegisterRequest) (StatusMessage, error) {
+	if err := validateFields(user); err != nil {
+		return StatusMessage{}, err
 	}
 
-	err = model.UserRegister(user.UserName, user.Email, user.PasswordMD5, user.RealName, user.Bio)
+	if details := checkUserTaken(user.UserName, user.Email); len(details) > 0 {
+		return StatusMessage{}, newRequestError(ErrInvalidParameter, nil, details...)
+	}
+
+	if err := checkProfilePolicy(user.UserName, user.RealName, user.Bio); err != nil {
+		return StatusMessage{}, err
+	}
+
+	err := model.UserRegister(user.UserName, user.Email, user.PasswordMD5, user.RealName, user.Bio)
 	if err != nil {
-		return ResponseInternalServerError(c, "Failed to create user into database.", err)
+		return StatusMessage{}, internalRequestError("Failed to create user into database.", err)
 	}
 
-	return ResponseOK(c, StatusMessage{
-		Status: "Register successfully.",
-	})
+	return StatusMessage{
+		Status: i18n.Translate(lang, "status.register"),
+	}, nil
 }
 
 type UserLoginResponse struct {
@@ -103,48 +117,111 @@ func UserLoginPOST(c echo.Context) error {
 		return err
 	}
 
-	user, err, e500 := FindUser(c, model.User{
+	resp, err := LoginUser(userRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 按 user_id、email 或 user_name 及密码登录，REST 与 gRPC 共用
+func LoginUser(userRequest model.User) (UserLoginResponse, error) {
+	user, err := findUser(model.User{
 		ID:       userRequest.ID,
 		UserName: userRequest.UserName,
 		Email:    userRequest.Email,
 	})
-	if e500 {
-		return err
-	}
 	if err != nil {
-		return ResponseBadRequest(c, "Find user failed.", err)
+		return UserLoginResponse{}, err
 	}
 
 	if user.Deleted {
-		return ResponseBadRequest(c, "This user has been deleted.", nil)
+		return UserLoginResponse{}, newRequestError(ErrUserDeleted, nil)
 	}
 
 	if !user.Verified {
-		return ResponseBadRequest(c, "This user has not been verified.", nil)
+		return UserLoginResponse{}, newRequestError(ErrUserNotVerified, nil)
 	}
 
 	if user.PasswordMD5 != userRequest.PasswordMD5 {
-		return ResponseBadRequest(c, "Wrong password.", nil)
+		return UserLoginResponse{}, newRequestError(ErrWrongPassword, nil)
+	}
+
+	if user.IsSuspended(time.Now()) {
+		return UserLoginResponse{}, suspendedError(user)
 	}
 
+	return issueTokens(user)
+}
+
+type UserRefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+/**
+ * 用 refresh token 换取新的 access token 及 refresh token
+ * 与登录一样检查用户状态，改名、删除或封禁后原有的 refresh token 不再可用
+ **/
+func UserRefreshPOST(c echo.Context) error {
+	logs.Debug("POST /user/refresh")
+
+	refreshRequest := UserRefreshRequest{}
+	_ok, err := Bind(c, &refreshRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.ParseRefreshToken(refreshRequest.RefreshToken)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	user, err := model.FindUserByID(claims.ID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrUnauthorized, err)
+		}
+		return ResponseInternalServerError(c, "Find user by ID failed.", err)
+	}
+	if user.UserName != claims.UserName {
+		return ResponseError(c, ErrUnauthorized, nil)
+	}
+
+	if user.Deleted {
+		return ResponseError(c, ErrUserDeleted, nil)
+	}
+
+	if user.IsSuspended(time.Now()) {
+		return ResponseSuspended(c, user)
+	}
+
+	resp, err := issueTokens(user)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 签发新的 access token 及 refresh token
+func issueTokens(user model.User) (UserLoginResponse, error) {
 	accessTokenString, accessTokenExpireAt, err := auth.GenerateAccessToken(&user)
 	if err != nil {
-		return ResponseInternalServerError(c, "Generate access token failed.", err)
+		return UserLoginResponse{}, internalRequestError("Generate access token failed.", err)
 	}
 
 	refreshTokenString, refreshTokenExpireAt, err := auth.GenerateRefreshToken(&user)
 	if err != nil {
-		return ResponseInternalServerError(c, "Generate refresh token failed.", err)
+		return UserLoginResponse{}, internalRequestError("Generate refresh token failed.", err)
 	}
 
-	return ResponseOK(c, UserLoginResponse{
+	return UserLoginResponse{
 		ID:                   user.ID,
 		UserName:             user.UserName,
 		AccessToken:          accessTokenString,
 		AccessTokenExpireAt:  accessTokenExpireAt.Unix(),
 		RefreshToken:         refreshTokenString,
 		RefreshTokenExpireAt: refreshTokenExpireAt.Unix(),
-	})
+	}, nil
 }
 
 func UserIsAuthGET(c echo.Context) error {
@@ -156,43 +233,163 @@ func UserIsAuthGET(c echo.Context) error {
 }
 
 type UserGETResponse struct {
-	ID       uint32 `json:"user_id"   `
-	UserName string `json:"user_name" `
-	Email    string `json:"email"     `
-	RealName string `json:"real_name" `
-	Bio      string `json:"bio"       `
-	Verified bool   `json:"verified"  `
-	Deleted  bool   `json:"deleted"   `
+	ID        uint32 `json:"user_id"   `
+	UserName  string `json:"user_name" `
+	Email     string `json:"email"     `
+	RealName  string `json:"real_name" `
+	Bio       string `json:"bio"       `
+	Verified  bool   `json:"verified"  `
+	Deleted   bool   `json:"deleted"   `
+	Suspended bool   `json:"suspended" `
+
+	AvatarURLs map[string]string `json:"avatar_urls"`
+	BannerURLs map[string]string `json:"banner_urls"`
+}
+
+// 用户 ID 可放在路径中，如 /user/1
+type UserGetRequest struct {
+	ID       uint32 `json:"user_id"   query:"user_id"   param:"user_id"`
+	UserName string `json:"user_name" query:"user_name"`
+	Email    string `json:"email"     query:"email"`
 }
 
 func UserGET(c echo.Context) error {
 	logs.Debug("GET /user")
 
-	userRequest := model.User{}
+	userRequest := UserGetRequest{}
 	_ok, err := Bind(c, &userRequest)
 	if !_ok {
 		return err
 	}
 
-	user, err, e500 := FindUser(c, model.User{
+	resp, err := GetUser(userRequest)
+	if err != nil {
+		return ResponseRequestError(c, err)
+	}
+	return ResponseOK(c, resp)
+}
+
+// 按 user_id、email 或 user_name 查找用户，REST 与 gRPC 共用
+func GetUser(userRequest UserGetRequest) (UserGETResponse, error) {
+	user, err := findUser(model.User{
 		ID:       userRequest.ID,
 		UserName: userRequest.UserName,
 		Email:    userRequest.Email,
 	})
-	if e500 {
+	if err != nil {
+		return UserGETResponse{}, err
+	}
+	return newUserGETResponse(user), nil
+}
+
+func newUserGETResponse(user model.User) UserGETResponse {
+	return UserGETResponse{
+		ID:        user.ID,
+		UserName:  user.UserName,
+		Email:     user.Email,
+		RealName:  user.RealName,
+		Bio:       user.Bio,
+		Verified:  user.Verified,
+		Deleted:   user.Deleted,
+		Suspended: user.IsSuspended(time.Now()),
+
+		AvatarURLs: profileImageURLs(avatarKind, user.ID, user.AvatarKey),
+		BannerURLs: profileImageURLs(bannerKind, user.ID, user.BannerKey),
+	}
+}
+
+type UserSuspendRequest struct {
+	ID        uint32 `json:"user_id" validate:"required"`
+	Reason    string `json:"reason"  validate:"required,max=500"`
+	Until     int64  `json:"until"`
+	Permanent bool   `json:"permanent"`
+}
+
+func UserSuspendPOST(c echo.Context) error {
+	logs.Debug("POST /user/suspend")
+
+	suspendRequest := UserSuspendRequest{}
+	_ok, err := Bind(c, &suspendRequest)
+	if !_ok {
+		return err
+	}
+
+	until := time.Unix(suspendRequest.Until, 0)
+	if !suspendRequest.Permanent && !until.After(time.Now()) {
+		return ResponseInvalidParameter(c, "until", "param.future")
+	}
+
+	err = model.SuspendUser(suspendRequest.ID, suspendRequest.Reason, until, suspendRequest.Permanent)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrUserNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to suspend user.", err)
+	}
+
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.suspend"),
+	})
+}
+
+type UserUnsuspendRequest struct {
+	ID uint32 `json:"user_id" validate:"required"`
+}
+
+func UserUnsuspendPOST(c echo.Context) error {
+	logs.Debug("POST /user/unsuspend")
+
+	unsuspendRequest := UserUnsuspendRequest{}
+	_ok, err := Bind(c, &unsuspendRequest)
+	if !_ok {
 		return err
 	}
+
+	err = model.UnsuspendUser(unsuspendRequest.ID)
 	if err != nil {
-		return ResponseBadRequest(c, "Find user failed.", err)
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrUserNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to unsuspend user.", err)
 	}
 
-	return ResponseOK(c, UserGETResponse{
-		ID:       user.ID,
-		UserName: user.UserName,
-		Email:    user.Email,
-		RealName: user.RealName,
-		Bio:      user.Bio,
-		Verified: user.Verified,
-		Deleted:  user.Deleted,
+	return ResponseOK(c, StatusMessage{
+		Status: Translate(c, "status.unsuspend"),
 	})
 }
+
+type UserProfileRequest struct {
+	RealName string `json:"real_name" validate:"max=50"`
+	Bio      string `json:"bio"       validate:"max=160"`
+}
+
+func UserProfilePOST(c echo.Context) error {
+	logs.Debug("POST /user/profile")
+
+	profileRequest := UserProfileRequest{}
+	_ok, err := Bind(c, &profileRequest)
+	if !_ok {
+		return err
+	}
+
+	claims, err := auth.GetClaimsFromHeader(c)
+	if err != nil {
+		return ResponseError(c, ErrUnauthorized, err)
+	}
+
+	realName := strings.TrimSpace(profileRequest.RealName)
+	bio := strings.TrimSpace(profileRequest.Bio)
+	if err := checkProfilePolicy(realName, bio); err != nil {
+		return ResponseRequestError(c, err)
+	}
+
+	user, err := model.UpdateUserProfile(claims.ID, realName, bio)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ResponseError(c, ErrUserNotFound, err)
+		}
+		return ResponseInternalServerError(c, "Failed to update profile.", err)
+	}
+
+	return ResponseOK(c, newUserGETResponse(user))
+}
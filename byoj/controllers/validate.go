@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+/**
+ * 请求结构体的声明式校验，由 Bind 在解析成功后自动执行
+ * 规则写在 validate 标签中，例如 `validate:"required,max=64"`、`validate:"omitempty,oneof=time random"`
+ * 字段名按 json 标签返回，嵌套字段以 "." 连接，切片元素带下标，如 "poll.options[1]"
+ **/
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// 校验请求，返回全部不符合规则的字段；obj 不是结构体或结构体指针时不校验
+func validateRequest(obj interface{}) []FieldError {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := validate.Struct(obj)
+	var violations validator.ValidationErrors
+	if !errors.As(err, &violations) {
+		return nil
+	}
+	details := make([]FieldError, 0, len(violations))
+	for _, v := range violations {
+		details = append(details, violationError(v))
+	}
+	return details
+}
+
+// 按 validate 标签校验请求，不经过 Bind 的 GraphQL 及 gRPC 调用同样校验
+func validateFields(obj interface{}) error {
+	if details := validateRequest(obj); len(details) > 0 {
+		return newRequestError(ErrInvalidParameter, nil, details...)
+	}
+	return nil
+}
+
+// 将 validator 的校验错误转换为字段级错误，Limit 取规则的参数
+func violationError(v validator.FieldError) FieldError {
+	field := v.Namespace()
+	// 去掉最外层的结构体名
+	if i := strings.IndexByte(field, '.'); i >= 0 {
+		field = field[i+1:]
+	}
+
+	var key string
+	var args []interface{}
+	isString := v.Kind() == reflect.String
+	isList := v.Kind() == reflect.Slice || v.Kind() == reflect.Array || v.Kind() == reflect.Map
+	switch v.Tag() {
+	case "required":
+		key = "param.required"
+	case "email":
+		key = "param.email"
+	case "oneof":
+		key = "param.oneof"
+		args = append(args, strings.Join(strings.Fields(v.Param()), ", "))
+	case "max", "lte":
+		switch {
+		case isString:
+			key = "param.max_length"
+		case isList:
+			key = "param.max_items"
+		default:
+			key = "param.max"
+		}
+		args = append(args, v.Param())
+	case "min", "gte":
+		switch {
+		case isString:
+			key = "param.min_length"
+		case isList:
+			key = "param.min_items"
+		default:
+			key = "param.min"
+		}
+		args = append(args, v.Param())
+	default:
+		key = "param.invalid"
+	}
+	detail := newFieldError(field, v.Tag(), key, args...)
+	// 参数不是数字的规则（如 oneof）没有 Limit
+	detail.Limit, _ = strconv.Atoi(v.Param())
+	return detail
+}
@@ -0,0 +1,41 @@
+package docs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+//go:embed ui.html
+var uiHTML []byte
+
+/**
+ * 返回 OpenAPI 文档的处理函数
+ * 首次请求时根据 routes 返回的路由表生成并缓存，因此注册顺序不影响结果
+ **/
+func SpecHandler(routes func() []*echo.Route, ops []Operation, info Info) echo.HandlerFunc {
+	var once sync.Once
+	var body []byte
+	var err error
+	return func(c echo.Context) error {
+		once.Do(func() {
+			var spec *Spec
+			spec, err = Generate(routes(), ops, info)
+			if err == nil {
+				body, err = json.Marshal(spec)
+			}
+		})
+		if err != nil {
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return c.Blob(http.StatusOK, echo.MIMEApplicationJSONCharsetUTF8, body)
+	}
+}
+
+// 文档页面，读取同源的 /openapi.json 渲染，不依赖外部资源
+func UIHandler(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, uiHTML)
+}
@@ -0,0 +1,620 @@
+package docs
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const openAPIVersion = "3.0.3"
+
+var versionPrefix = regexp.MustCompile(`^v[0-9]+$`)
+
+// 查询参数或路径参数
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+/**
+ * 单个接口的文档信息，按 Handler 与路由表中的路由对应
+ * Request 对 GET 请求生成查询参数，对其他请求生成 JSON 请求体
+ * Response 为 ResponseStruct 中 data 字段的内容，Raw 为 true 时为整个响应体
+ **/
+type Operation struct {
+	Handler     echo.HandlerFunc
+	Summary     string
+	Description string
+	// 需要在请求头中携带 access token
+	Auth bool
+	// 仅管理员可调用
+	Admin    bool
+	Request  interface{}
+	Query    []Param
+	Response interface{}
+	// 以 multipart/form-data 上传文件时的字段名
+	Upload string
+	// 响应为文件内容而非 JSON
+	Binary bool
+	// 响应不包装在 {code, msg, data} 中，如 GraphQL
+	Raw bool
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Spec struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       Info                             `json:"info"`
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components components                       `json:"components"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *requestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type requestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+const bearerAuth = "bearerAuth"
+
+// 与 echo 记录的路由名一致
+func HandlerName(h echo.HandlerFunc) string {
+	return runtime.FuncForPC(reflect.ValueOf(h).Pointer()).Name()
+}
+
+// 只有 controllers 中的处理函数需要文档，echo 分组中间件生成的路由及文档本身的路由除外
+func documented(route *echo.Route) bool {
+	return strings.HasPrefix(route.Name, "byoj/controllers.")
+}
+
+type routeKey struct {
+	method string
+	path   string
+}
+
+/**
+ * 根据路由表及接口文档信息生成 OpenAPI 文档
+ * 路由缺少文档或文档对应的处理函数未注册路由时返回错误，错误信息列出所有不一致之处
+ **/
+func Generate(routes []*echo.Route, ops []Operation, info Info) (*Spec, error) {
+	byName := make(map[string]Operation, len(ops))
+	for _, op := range ops {
+		byName[HandlerName(op.Handler)] = op
+	}
+
+	// 同一处理函数同时注册了 "/x" 与 "/x/" 时只保留前者
+	registered := make(map[routeKey]string)
+	for _, route := range routes {
+		registered[routeKey{route.Method, route.Path}] = route.Name
+	}
+
+	var problems []string
+	used := make(map[string]bool)
+	g := &generator{schemas: make(map[string]*Schema), names: make(map[string]reflect.Type)}
+	spec := &Spec{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*operation),
+		Components: components{
+			Schemas: g.schemas,
+			SecuritySchemes: map[string]securityScheme{
+				bearerAuth: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+			},
+		},
+	}
+	for _, route := range routes {
+		if !documented(route) {
+			continue
+		}
+		if trimmed := strings.TrimSuffix(route.Path, "/"); trimmed != route.Path && trimmed != "" &&
+			registered[routeKey{route.Method, trimmed}] == route.Name {
+			continue
+		}
+		op, ok := byName[route.Name]
+		if !ok {
+			problems = append(problems, "undocumented route "+route.Method+" "+route.Path+" ("+route.Name+")")
+			continue
+		}
+		used[route.Name] = true
+
+		path := openAPIPath(route.Path)
+		if spec.Paths[path] == nil {
+			spec.Paths[path] = make(map[string]*operation)
+		}
+		spec.Paths[path][strings.ToLower(route.Method)] = g.operation(route, op)
+	}
+	problems = append(problems, uniqueOperationIDs(spec.Paths)...)
+	for name := range byName {
+		if !used[name] {
+			problems = append(problems, "documented handler without route "+name)
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return spec, errors.New(strings.Join(problems, "; "))
+	}
+	return spec, nil
+}
+
+/**
+ * operationId 须唯一，处理函数注册在多个路径上时，如 /user 与 /user/{user_id}，带路径参数的加上参数名
+ * 仍然重复时返回问题列表
+ **/
+func uniqueOperationIDs(paths map[string]map[string]*operation) []string {
+	byID := make(map[string][]*operation)
+	for _, methods := range paths {
+		for _, o := range methods {
+			byID[o.OperationID] = append(byID[o.OperationID], o)
+		}
+	}
+	for _, ops := range byID {
+		if len(ops) < 2 {
+			continue
+		}
+		for _, o := range ops {
+			suffix := ""
+			for _, p := range o.Parameters {
+				if p.In == "path" {
+					suffix += camelCase(p.Name)
+				}
+			}
+			if suffix != "" {
+				o.OperationID += "By" + suffix
+			}
+		}
+	}
+
+	var problems []string
+	seen := make(map[string]bool)
+	for _, methods := range paths {
+		for _, o := range methods {
+			if seen[o.OperationID] {
+				problems = append(problems, "duplicate operationId "+o.OperationID)
+			}
+			seen[o.OperationID] = true
+		}
+	}
+	return problems
+}
+
+// "user_id" 转换为 "UserId"
+func camelCase(name string) string {
+	words := strings.Split(name, "_")
+	for i, word := range words {
+		if word != "" {
+			words[i] = strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return strings.Join(words, "")
+}
+
+// "/media/:id" 转换为 "/media/{id}"
+func openAPIPath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		if strings.HasPrefix(seg, ":") {
+			segs[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segs, "/")
+}
+
+type generator struct {
+	schemas map[string]*Schema
+	names   map[string]reflect.Type
+}
+
+func (g *generator) operation(route *echo.Route, op Operation) *operation {
+	name := route.Name[strings.LastIndex(route.Name, ".")+1:]
+	o := &operation{
+		Summary:     op.Summary,
+		Description: op.Description,
+		OperationID: name,
+		Responses:   make(map[string]response),
+	}
+	// 按版本前缀之后的第一段路径分组
+	segs := strings.Split(strings.TrimPrefix(route.Path, "/"), "/")
+	if len(segs) > 1 && versionPrefix.MatchString(segs[0]) {
+		segs = segs[1:]
+	}
+	if segs[0] != "" {
+		o.Tags = []string{segs[0]}
+	} else {
+		o.Tags = []string{"index"}
+	}
+
+	inPath := make(map[string]bool)
+	for _, seg := range strings.Split(route.Path, "/") {
+		if strings.HasPrefix(seg, ":") {
+			inPath[seg[1:]] = true
+			o.Parameters = append(o.Parameters, parameter{
+				Name: seg[1:], In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	for _, p := range op.Query {
+		typ := p.Type
+		if typ == "" {
+			typ = "string"
+		}
+		o.Parameters = append(o.Parameters, parameter{
+			Name: p.Name, In: "query", Description: p.Description, Required: p.Required, Schema: &Schema{Type: typ},
+		})
+	}
+
+	if op.Request != nil {
+		if route.Method == http.MethodGet || route.Method == http.MethodDelete {
+			// 已在路径中的参数不再作为查询参数
+			for _, p := range g.queryParameters(reflect.TypeOf(op.Request)) {
+				if !inPath[p.Name] {
+					o.Parameters = append(o.Parameters, p)
+				}
+			}
+		} else {
+			o.RequestBody = &requestBody{
+				Required: true,
+				Content:  map[string]mediaType{echo.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(op.Request))}},
+			}
+		}
+	}
+	if op.Upload != "" {
+		o.RequestBody = &requestBody{
+			Required: true,
+			Content: map[string]mediaType{echo.MIMEMultipartForm: {Schema: &Schema{
+				Type:       "object",
+				Properties: map[string]*Schema{op.Upload: {Type: "string", Format: "binary"}},
+				Required:   []string{op.Upload},
+			}}},
+		}
+	}
+
+	if op.Binary {
+		o.Responses["200"] = response{
+			Description: "File content.",
+			Content:     map[string]mediaType{"application/octet-stream": {Schema: &Schema{Type: "string", Format: "binary"}}},
+		}
+	} else if op.Raw {
+		o.Responses["200"] = response{
+			Description: "OK",
+			Content:     map[string]mediaType{echo.MIMEApplicationJSON: {Schema: g.schema(reflect.TypeOf(op.Response))}},
+		}
+	} else {
+		var data *Schema
+		if op.Response != nil {
+			data = g.schema(reflect.TypeOf(op.Response))
+		}
+		o.Responses["200"] = g.envelope("OK", data)
+	}
+	errorData := g.schema(reflect.TypeOf(ErrorMessage{}))
+	o.Responses["400"] = g.envelope("Bad Request", errorData)
+	if op.Auth || op.Admin {
+		o.Security = []map[string][]string{{bearerAuth: {}}}
+		o.Responses["401"] = g.envelope("Unauthorized", errorData)
+		o.Responses["403"] = g.envelope("Forbidden", errorData)
+	}
+	o.Responses["500"] = g.envelope("Internal Server Error", errorData)
+	return o
+}
+
+// 与 controllers.ErrorMessage、controllers.FieldError 结构一致，docs 不依赖 controllers 以免循环引用
+type ErrorMessage struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"msg"`
+	Details   []FieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule,omitempty"`
+	Message string `json:"msg"`
+	Limit   int    `json:"limit,omitempty"`
+	Actual  int    `json:"actual,omitempty"`
+}
+
+// 所有 JSON 响应都包装在 {code, msg, data} 中
+func (g *generator) envelope(description string, data *Schema) response {
+	if data == nil {
+		data = &Schema{Nullable: true}
+	}
+	return response{
+		Description: description,
+		Content: map[string]mediaType{echo.MIMEApplicationJSON: {Schema: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"code": {Type: "integer", Format: "int32"},
+				"msg":  {Type: "string"},
+				"data": data,
+			},
+			Required: []string{"code", "msg", "data"},
+		}}},
+	}
+}
+
+// GET 请求的结构体字段按 query 标签生成查询参数，没有 query 标签时使用 json 标签
+func (g *generator) queryParameters(t reflect.Type) []parameter {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	var params []parameter
+	for _, f := range fields(t) {
+		name := tagName(f, "query")
+		if name == "" {
+			name = tagName(f, "json")
+		}
+		if name == "" || name == "-" {
+			continue
+		}
+		schema := g.schema(f.Type)
+		if schema.Ref != "" || schema.Type == "object" || schema.Type == "array" {
+			continue
+		}
+		required := applyValidation(schema, f.Tag.Get("validate"))
+		params = append(params, parameter{Name: name, In: "query", Required: required, Schema: schema})
+	}
+	return params
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	nullTimeType = reflect.TypeOf(sql.NullTime{})
+	rawJSONType  = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	nullable := false
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		nullable = true
+	}
+
+	var s *Schema
+	switch {
+	case t == timeType:
+		s = &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.ConvertibleTo(nullTimeType):
+		s = &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case t == rawJSONType:
+		s = &Schema{}
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			s = &Schema{Type: "boolean"}
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+			s = &Schema{Type: "integer", Format: "int32"}
+		case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+			s = &Schema{Type: "integer", Format: "int64"}
+		case reflect.Float32:
+			s = &Schema{Type: "number", Format: "float"}
+		case reflect.Float64:
+			s = &Schema{Type: "number", Format: "double"}
+		case reflect.String:
+			s = &Schema{Type: "string"}
+		case reflect.Slice, reflect.Array:
+			if t.Elem().Kind() == reflect.Uint8 {
+				s = &Schema{Type: "string", Format: "byte"}
+			} else {
+				s = &Schema{Type: "array", Items: g.schema(t.Elem())}
+			}
+		case reflect.Map:
+			s = &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+		case reflect.Struct:
+			s = g.structRef(t)
+		default:
+			s = &Schema{}
+		}
+	}
+	if nullable && s.Ref == "" {
+		s.Nullable = true
+	}
+	return s
+}
+
+// 具名结构体放入 components 并返回引用，重名时加上包名区分
+func (g *generator) structRef(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.structSchema(t)
+	}
+	name := t.Name()
+	if other, ok := g.names[name]; ok && other != t {
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + t.Name()
+	}
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if _, ok := g.names[name]; ok {
+		return ref
+	}
+	g.names[name] = t
+	// 先占位，防止自引用的结构体无限递归
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.structSchema(t)
+	return ref
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for _, f := range fields(t) {
+		name := tagName(f, "json")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+		if applyValidation(s.Properties[name], f.Tag.Get("validate")) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+/**
+ * 将 validate 标签中的规则写入字段的 schema，返回字段是否必填
+ * 只处理 dive 之前作用于字段本身的规则，引用类型的字段不附加约束
+ **/
+func applyValidation(s *Schema, tag string) (required bool) {
+	for _, rule := range strings.Split(tag, ",") {
+		if rule == "dive" {
+			break
+		}
+		name, param, _ := strings.Cut(rule, "=")
+		if name == "required" {
+			required = true
+			continue
+		}
+		if s.Ref != "" {
+			continue
+		}
+		n, err := strconv.Atoi(param)
+		hasNumber := err == nil
+		switch {
+		case name == "email":
+			s.Format = "email"
+		case name == "oneof":
+			s.Enum = strings.Fields(param)
+		case (name == "max" || name == "lte") && hasNumber:
+			switch s.Type {
+			case "string":
+				s.MaxLength = &n
+			case "array":
+				s.MaxItems = &n
+			default:
+				s.Maximum = &n
+			}
+		case (name == "min" || name == "gte") && hasNumber:
+			switch s.Type {
+			case "string":
+				s.MinLength = &n
+			case "array":
+				s.MinItems = &n
+			default:
+				s.Minimum = &n
+			}
+		}
+	}
+	return required
+}
+
+// 与 encoding/json 一致，展开匿名嵌入的结构体字段，外层字段优先
+func fields(t reflect.Type) []reflect.StructField {
+	var list []reflect.StructField
+	seen := make(map[string]bool)
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		var embedded []reflect.Type
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && tagName(f, "json") == "" {
+				ft := f.Type
+				for ft.Kind() == reflect.Ptr {
+					ft = ft.Elem()
+				}
+				if ft.Kind() == reflect.Struct {
+					embedded = append(embedded, ft)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			name := tagName(f, "json")
+			if name == "" {
+				name = f.Name
+			}
+			if !seen[name] {
+				seen[name] = true
+				list = append(list, f)
+			}
+		}
+		for _, e := range embedded {
+			walk(e)
+		}
+	}
+	walk(t)
+	return list
+}
+
+func tagName(f reflect.StructField, key string) string {
+	tag := f.Tag.Get(key)
+	if i := strings.Index(tag, ","); i >= 0 {
+		tag = tag[:i]
+	}
+	return tag
+}
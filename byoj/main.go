@@ -1,10 +1,16 @@
 package main
 
 import (
+	"byoj/controllers"
 	"byoj/controllers/auth"
+	"byoj/media"
 	"byoj/model"
+	"byoj/preview"
 	"byoj/shared/server"
 	"byoj/shared/yamlconfig"
+	"byoj/storage"
+	"byoj/tasks"
+	"byoj/utils/content"
 )
 
 func main() {
@@ -28,6 +34,33 @@ func main() {
 		panic(err)
 	}
 
+	err = storage.InitStorage(configuration.Storage)
+	if err != nil {
+		panic(err)
+	}
+
+	err = media.InitMedia(configuration.Media)
+	if err != nil {
+		panic(err)
+	}
+
+	err = content.InitContent(configuration.Content)
+	if err != nil {
+		panic(err)
+	}
+
+	err = preview.InitPreview(configuration.Preview)
+	if err != nil {
+		panic(err)
+	}
+
+	err = controllers.InitGraphQL(configuration.GraphQL)
+	if err != nil {
+		panic(err)
+	}
+
+	tasks.Start()
+
 	err = server.Run(configuration.Server)
 	if err != nil {
 		panic(err)
@@ -0,0 +1,164 @@
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+const jpegQuality = 85
+
+type ImageSize struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+var (
+	AvatarSizes = []ImageSize{
+		{Name: "small", Width: 48, Height: 48},
+		{Name: "medium", Width: 200, Height: 200},
+		{Name: "large", Width: 400, Height: 400},
+	}
+	BannerSizes = []ImageSize{
+		{Name: "small", Width: 600, Height: 200},
+		{Name: "large", Width: 1500, Height: 500},
+	}
+)
+
+/**
+ * 解码上传的图片，只接受图片类型
+ * JPEG 按 EXIF 方向旋转后返回，Info 中的宽高为旋转后的尺寸
+ * 重新编码输出时不会保留 EXIF 等元数据
+ **/
+func DecodeImage(r io.ReadSeeker, size int64) (image.Image, Info, error) {
+	info, img, err := inspect(r, size)
+	if err != nil {
+		return nil, info, err
+	}
+	if info.Kind != KindImage {
+		return nil, info, ErrUnsupportedType
+	}
+
+	if info.ContentType == "image/jpeg" {
+		head, err := io.ReadAll(io.LimitReader(r, maxExifHeader))
+		if err != nil {
+			return nil, info, err
+		}
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return nil, info, err
+		}
+		img = applyOrientation(img, jpegOrientation(head))
+		info.Width, info.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	}
+	return img, info, nil
+}
+
+/**
+ * 按目标宽高比裁剪图片
+ * @param: rect 用户指定的裁剪区域，为空时居中裁剪
+ **/
+func Crop(img image.Image, rect image.Rectangle, width int, height int) image.Image {
+	bounds := img.Bounds()
+	if !rect.Empty() {
+		bounds = rect.Add(bounds.Min).Intersect(bounds)
+		if bounds.Empty() {
+			bounds = img.Bounds()
+		}
+	}
+
+	w, h := bounds.Dx(), bounds.Dy()
+	if w*height > h*width {
+		w = h * width / height
+	} else {
+		h = w * height / width
+	}
+	if w == 0 || h == 0 {
+		return img
+	}
+	x := bounds.Min.X + (bounds.Dx()-w)/2
+	y := bounds.Min.Y + (bounds.Dy()-h)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), img, image.Point{X: x, Y: y}, draw.Src)
+	return dst
+}
+
+// 按区域平均缩放，适用于缩小；放大时退化为最近邻
+func Resize(img image.Image, width int, height int) *image.RGBA {
+	src := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(src, src.Bounds(), img, img.Bounds().Min, draw.Src)
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += uint32(src.Pix[i])
+					g += uint32(src.Pix[i+1])
+					b += uint32(src.Pix[i+2])
+					a += uint32(src.Pix[i+3])
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// PNG 与 GIF 可能带透明通道，输出 PNG，其余输出 JPEG
+func Encode(img image.Image, sourceType string) (data []byte, contentType string, extension string, err error) {
+	var buf bytes.Buffer
+	if sourceType == "image/png" || sourceType == "image/gif" {
+		err = png.Encode(&buf, img)
+		return buf.Bytes(), "image/png", ".png", err
+	}
+	err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	return buf.Bytes(), "image/jpeg", ".jpg", err
+}
+
+// 根据 seed 生成 5x5 左右对称的默认头像
+func Identicon(seed string, size int) image.Image {
+	hash := sha256.Sum256([]byte(seed))
+	fg := color.RGBA{R: hash[0]/2 + 64, G: hash[1]/2 + 64, B: hash[2]/2 + 64, A: 255}
+	bg := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	const grid = 5
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+	padding := size / 10
+	cell := (size - 2*padding) / grid
+	if cell == 0 {
+		return img
+	}
+	offset := (size - cell*grid) / 2
+	for y := 0; y < grid; y++ {
+		for x := 0; x < (grid+1)/2; x++ {
+			if hash[3+y*3+x]%2 == 0 {
+				continue
+			}
+			for _, cx := range []int{x, grid - 1 - x} {
+				r := image.Rect(offset+cx*cell, offset+y*cell, offset+(cx+1)*cell, offset+(y+1)*cell)
+				draw.Draw(img, r, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+	return img
+}
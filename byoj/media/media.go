@@ -0,0 +1,157 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+)
+
+const (
+	KindImage = "image"
+	KindVideo = "video"
+
+	// 防止体积很小但尺寸巨大的图片解码时占用过多内存
+	maxImagePixels = 40000000
+
+	blurhashXComponents = 4
+	blurhashYComponents = 3
+)
+
+type Media struct {
+	MaxImageSize int64 `yaml:"max-image-size"`
+	MaxVideoSize int64 `yaml:"max-video-size"`
+}
+
+var (
+	maxImageSize int64 = 10 << 20
+	maxVideoSize int64 = 100 << 20
+)
+
+// 按文件内容识别出的类型，不信任客户端提供的 Content-Type
+var allowedTypes = map[string]string{
+	"image/jpeg": KindImage,
+	"image/png":  KindImage,
+	"image/gif":  KindImage,
+	"video/mp4":  KindVideo,
+	"video/webm": KindVideo,
+}
+
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("media file is too large")
+	ErrCorruptImage    = errors.New("image cannot be decoded")
+)
+
+type Info struct {
+	ContentType string
+	Kind        string
+	Extension   string
+	Size        int64
+	Width       int
+	Height      int
+	Blurhash    string
+}
+
+func InitMedia(m Media) error {
+	if m.MaxImageSize > 0 {
+		maxImageSize = m.MaxImageSize
+	}
+	if m.MaxVideoSize > 0 {
+		maxVideoSize = m.MaxVideoSize
+	}
+	return nil
+}
+
+// 上传图片允许的最大体积
+func MaxImageSize() int64 {
+	return maxImageSize
+}
+
+// 上传文件允许的最大体积
+func MaxUploadSize() int64 {
+	if maxImageSize > maxVideoSize {
+		return maxImageSize
+	}
+	return maxVideoSize
+}
+
+/**
+ * 检查上传的文件，识别类型并校验大小
+ * 图片会完整解码以获取尺寸和 blurhash，视频只读取文件头
+ * 调用后 r 的读取位置会被重置到开头
+ **/
+func Inspect(r io.ReadSeeker, size int64) (Info, error) {
+	info, _, err := inspect(r, size)
+	return info, err
+}
+
+// 与 Inspect 相同，同时返回解码后的图片，视频返回 nil
+func inspect(r io.ReadSeeker, size int64) (Info, image.Image, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return Info{}, nil, err
+	}
+	contentType := http.DetectContentType(head[:n])
+	kind, ok := allowedTypes[contentType]
+	if !ok {
+		return Info{}, nil, ErrUnsupportedType
+	}
+
+	info := Info{
+		ContentType: contentType,
+		Kind:        kind,
+		Extension:   extensions[contentType],
+		Size:        size,
+	}
+	if (kind == KindImage && size > maxImageSize) || (kind == KindVideo && size > maxVideoSize) {
+		return info, nil, ErrTooLarge
+	}
+
+	if _, err = r.Seek(0, io.SeekStart); err != nil {
+		return info, nil, err
+	}
+	var img image.Image
+	if kind == KindImage {
+		data, err := io.ReadAll(io.LimitReader(r, maxImageSize+1))
+		if err != nil {
+			return info, nil, err
+		}
+		if int64(len(data)) > maxImageSize {
+			return info, nil, ErrTooLarge
+		}
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return info, nil, ErrCorruptImage
+		}
+		if int64(config.Width)*int64(config.Height) > maxImagePixels {
+			return info, nil, ErrTooLarge
+		}
+		img, _, err = image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return info, nil, ErrCorruptImage
+		}
+		info.Width, info.Height = img.Bounds().Dx(), img.Bounds().Dy()
+		info.Blurhash, err = Blurhash(img, blurhashXComponents, blurhashYComponents)
+		if err != nil {
+			return info, nil, err
+		}
+		if _, err = r.Seek(0, io.SeekStart); err != nil {
+			return info, nil, err
+		}
+	}
+	return info, img, nil
+}
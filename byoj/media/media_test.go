@@ -0,0 +1,140 @@
+package media_test
+
+import (
+	"byoj/media"
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 30))
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 40; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 6), G: uint8(y * 8), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := media.Inspect(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "image/png" || info.Width != 40 || info.Height != 30 {
+		t.Fatalf("Inspect() = %+v", info)
+	}
+	// 1 位尺寸 + 1 位最大值 + 4 位 DC + 11 个 AC 分量各 2 位
+	if len(info.Blurhash) != 28 {
+		t.Fatalf("len(Blurhash) = %d, want 28", len(info.Blurhash))
+	}
+
+	_, err = media.Inspect(bytes.NewReader([]byte("<html><script>alert(1)</script></html>")), 38)
+	if err != media.ErrUnsupportedType {
+		t.Fatalf("Inspect() error = %v, want ErrUnsupportedType", err)
+	}
+}
+
+func TestBlurhashSolidColor(t *testing.T) {
+	img := image.NewUniform(color.RGBA{R: 255, G: 255, B: 255, A: 255})
+	hash, err := media.Blurhash(&boundedImage{img, image.Rect(0, 0, 8, 8)}, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 单分量时只有尺寸、最大值和 DC，白色的 DC 为 0xFFFFFF
+	if hash != "00TSUA" {
+		t.Fatalf("Blurhash() = %q, want %q", hash, "00TSUA")
+	}
+}
+
+type boundedImage struct {
+	image.Image
+	bounds image.Rectangle
+}
+
+func (b *boundedImage) Bounds() image.Rectangle {
+	return b.bounds
+}
+
+func TestCropAndResize(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 300, 120))
+	cropped := media.Crop(img, image.Rectangle{}, 1, 1)
+	if cropped.Bounds().Dx() != 120 || cropped.Bounds().Dy() != 120 {
+		t.Fatalf("Crop() bounds = %v, want 120x120", cropped.Bounds())
+	}
+
+	cropped = media.Crop(img, image.Rect(10, 10, 110, 60), 1, 1)
+	if cropped.Bounds().Dx() != 50 || cropped.Bounds().Dy() != 50 {
+		t.Fatalf("Crop() with rect bounds = %v, want 50x50", cropped.Bounds())
+	}
+
+	resized := media.Resize(cropped, 48, 48)
+	if resized.Bounds().Dx() != 48 || resized.Bounds().Dy() != 48 {
+		t.Fatalf("Resize() bounds = %v, want 48x48", resized.Bounds())
+	}
+}
+
+// 在 JPEG 的 SOI 之后插入只含 Orientation 标签的 APP1 段，大端字节序
+func withOrientation(jpg []byte, orientation uint16) []byte {
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1,
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, byte(orientation >> 8), byte(orientation), 0, 0,
+		0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+	return append(append(append([]byte{}, jpg[:2]...), segment...), jpg[2:]...)
+}
+
+func TestDecodeImageOrientation(t *testing.T) {
+	// 左半红色、右半蓝色
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= 16 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}); err != nil {
+		t.Fatal(err)
+	}
+
+	red := func(c color.Color) bool {
+		r, _, b, _ := c.RGBA()
+		return r > b
+	}
+	cases := []struct {
+		orientation   uint16
+		width, height int
+		redX, redY    int
+		blueX, blueY  int
+	}{
+		{1, 32, 16, 4, 8, 28, 8},
+		{3, 32, 16, 28, 8, 4, 8},
+		// 顺时针旋转 90 度后左半部分在上方
+		{6, 16, 32, 8, 4, 8, 28},
+		{8, 16, 32, 8, 28, 8, 4},
+	}
+	for _, tc := range cases {
+		data := withOrientation(buf.Bytes(), tc.orientation)
+		decoded, info, err := media.DecodeImage(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b := decoded.Bounds()
+		if b.Dx() != tc.width || b.Dy() != tc.height || info.Width != tc.width || info.Height != tc.height {
+			t.Errorf("orientation %d: bounds %v, info %dx%d", tc.orientation, b, info.Width, info.Height)
+			continue
+		}
+		if !red(decoded.At(b.Min.X+tc.redX, b.Min.Y+tc.redY)) || red(decoded.At(b.Min.X+tc.blueX, b.Min.Y+tc.blueY)) {
+			t.Errorf("orientation %d: image is not rotated as expected", tc.orientation)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// 每条帖子最多附带的媒体数量
+const PostAttachmentLimit = 4
+
+var ErrInvalidAttachment = errors.New("attachment not found, not owned by author or already attached")
+
+type Attachment struct {
+	ID          uint32         `json:"media_id"     gorm:"primaryKey;unique;not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at"`
+	OwnerID     uint32         `json:"user_id"      gorm:"not null;index"`
+	PostID      uint32         `json:"post_id"      gorm:"not null;index"`
+	Key         string         `json:"key"          gorm:"not null;unique"`
+	ContentType string         `json:"content_type" gorm:"not null"`
+	Size        int64          `json:"size"         gorm:"not null"`
+	Width       int            `json:"width"`
+	Height      int            `json:"height"`
+	AltText     string         `json:"alt_text"`
+	Blurhash    string         `json:"blurhash"`
+}
+
+func CreateAttachment(attachment Attachment) (Attachment, error) {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Create(&attachment)
+	if result.Error != nil {
+		logs.Warn("Create attachment failed.", zap.Error(result.Error))
+		m.Abort()
+		return attachment, result.Error
+	}
+
+	m.tx.Commit()
+	return attachment, nil
+}
+
+func FindAttachmentByID(attachmentID uint32) (Attachment, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var attachment Attachment
+	result := m.tx.First(&attachment, attachmentID)
+	if result.Error != nil {
+		logs.Info("Find attachment by id failed.", zap.Error(result.Error))
+		m.Abort()
+		return attachment, result.Error
+	}
+
+	m.tx.Commit()
+	return attachment, nil
+}
+
+// 按帖子分组返回附件，组内按上传顺序排列
+func GetAttachmentsByPostIDs(postIDs []uint32) (map[uint32][]Attachment, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var attachments []Attachment
+	mp := make(map[uint32][]Attachment)
+	result := m.tx.Model(&Attachment{}).Where("post_id IN ?", postIDs).Order("id").Find(&attachments)
+	if result.Error != nil {
+		logs.Info("Find attachments by post ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return mp, result.Error
+	}
+
+	m.tx.Commit()
+	for _, attachment := range attachments {
+		mp[attachment.PostID] = append(mp[attachment.PostID], attachment)
+	}
+	return mp, nil
+}
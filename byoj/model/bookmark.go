@@ -0,0 +1,102 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+type Bookmark struct {
+	ID        uint32    `json:"bookmark_id" gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint32    `json:"user_id"     gorm:"not null;uniqueIndex:idx_bookmarks_user_post"`
+	PostID    uint32    `json:"post_id"     gorm:"not null;uniqueIndex:idx_bookmarks_user_post"`
+}
+
+// 重复收藏不会报错
+func CreateBookmark(userID uint32, postID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Bookmark{
+		UserID: userID,
+		PostID: postID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create bookmark failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func DeleteBookmark(userID uint32, postID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&Bookmark{})
+	if result.Error != nil {
+		logs.Warn("Delete bookmark failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+/**
+ * 获取用户收藏的帖子，按收藏时间倒序
+ * @param: beforeID 只返回收藏 ID 小于该值的记录，为 0 不限制
+ * @param: limit 限制结果数量
+ **/
+func GetBookmarks(userID uint32, beforeID uint32, limit int) ([]Bookmark, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var bookmarks []Bookmark
+	result := m.tx.Model(&Bookmark{}).
+		Joins("JOIN posts ON posts.id = bookmarks.post_id AND posts.deleted_at IS NULL").
+		Where("bookmarks.user_id = ?", userID).
+		Where("posts.is_public = ? OR posts.user_id = ?", true, userID)
+	if beforeID > 0 {
+		result = result.Where("bookmarks.id < ?", beforeID)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	result = result.Order("bookmarks.id desc").Limit(limit).Find(&bookmarks)
+	if result.Error != nil {
+		logs.Info("Find bookmarks failed.", zap.Error(result.Error))
+		m.Abort()
+		return bookmarks, result.Error
+	}
+
+	m.tx.Commit()
+	return bookmarks, nil
+}
+
+// 返回 postIDs 中已被用户收藏的帖子
+func GetBookmarkedPostIDs(userID uint32, postIDs []uint32) (map[uint32]bool, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var ids []uint32
+	bookmarked := make(map[uint32]bool)
+	result := m.tx.Model(&Bookmark{}).Where("user_id = ? AND post_id IN ?", userID, postIDs).Pluck("post_id", &ids)
+	if result.Error != nil {
+		logs.Info("Find bookmarked post ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return bookmarked, result.Error
+	}
+
+	m.tx.Commit()
+	for _, id := range ids {
+		bookmarked[id] = true
+	}
+	return bookmarked, nil
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"byoj/utils/logs"
+	"database/sql"
 	"strconv"
 
 	"go.uber.org/zap"
@@ -39,6 +40,16 @@ func Connect(d Database) error {
 	return err
 }
 
+// 使用已打开的数据库连接，如测试中模拟的连接
+func ConnectWith(conn *sql.DB) error {
+	var err error
+	db, err = gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		logs.Error("Failed to use database connection.", zap.Error(err))
+	}
+	return err
+}
+
 // Almost used for creating tables
 func AutoMigrateTable(dst ...interface{}) error {
 	for _, d := range dst {
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 每个用户最多可设置的屏蔽词数量
+const MutedWordLimit = 200
+
+var ErrTooManyMutedWords = errors.New("too many muted words")
+
+// 管理员维护的内容过滤规则，Action 取值见 policy 包
+type FilterRule struct {
+	ID        uint32    `json:"rule_id"    gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy uint32    `json:"created_by" gorm:"not null"`
+	Pattern   string    `json:"pattern"    gorm:"not null"`
+	IsRegex   bool      `json:"is_regex"   gorm:"not null;default:false"`
+	Action    string    `json:"action"     gorm:"not null"`
+}
+
+// 用户自己设置的屏蔽词，命中的帖子不会出现在该用户看到的列表中
+type MutedWord struct {
+	ID        uint32    `json:"muted_word_id" gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint32    `json:"user_id"       gorm:"not null;uniqueIndex:idx_muted_words_user_word"`
+	Word      string    `json:"word"          gorm:"not null;uniqueIndex:idx_muted_words_user_word"`
+}
+
+func CreateFilterRule(rule FilterRule) (FilterRule, error) {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Create(&rule)
+	if result.Error != nil {
+		logs.Warn("Create filter rule failed.", zap.Error(result.Error))
+		m.Abort()
+		return rule, result.Error
+	}
+
+	m.tx.Commit()
+	return rule, nil
+}
+
+func DeleteFilterRule(ruleID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Delete(&FilterRule{}, ruleID)
+	if result.Error != nil {
+		logs.Warn("Delete filter rule failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return gorm.ErrRecordNotFound
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func GetFilterRules() ([]FilterRule, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var rules []FilterRule
+	result := m.tx.Order("id").Find(&rules)
+	if result.Error != nil {
+		logs.Info("Find filter rules failed.", zap.Error(result.Error))
+		m.Abort()
+		return rules, result.Error
+	}
+
+	m.tx.Commit()
+	return rules, nil
+}
+
+// 重复添加不会报错，超过 MutedWordLimit 时返回 ErrTooManyMutedWords
+func CreateMutedWord(userID uint32, word string) error {
+	m := GetModel()
+	defer m.Close()
+
+	var count int64
+	result := m.tx.Model(&MutedWord{}).Where("user_id = ?", userID).Count(&count)
+	if result.Error != nil {
+		logs.Info("Count muted words failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if count >= MutedWordLimit {
+		m.Abort()
+		return ErrTooManyMutedWords
+	}
+
+	result = m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&MutedWord{
+		UserID: userID,
+		Word:   word,
+	})
+	if result.Error != nil {
+		logs.Warn("Create muted word failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func DeleteMutedWord(userID uint32, word string) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("user_id = ? AND word = ?", userID, word).Delete(&MutedWord{})
+	if result.Error != nil {
+		logs.Warn("Delete muted word failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func GetMutedWords(userID uint32) ([]MutedWord, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var words []MutedWord
+	result := m.tx.Where("user_id = ?", userID).Order("id").Find(&words)
+	if result.Error != nil {
+		logs.Info("Find muted words failed.", zap.Error(result.Error))
+		m.Abort()
+		return words, result.Error
+	}
+
+	m.tx.Commit()
+	return words, nil
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+type Follow struct {
+	ID         uint32    `json:"follow_id"   gorm:"primaryKey;unique;not null"`
+	CreatedAt  time.Time `json:"created_at"`
+	FollowerID uint32    `json:"follower_id" gorm:"not null;uniqueIndex:idx_follows_follower_followee"`
+	FolloweeID uint32    `json:"followee_id" gorm:"not null;uniqueIndex:idx_follows_follower_followee;index"`
+}
+
+type Block struct {
+	ID        uint32    `json:"block_id"   gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	BlockerID uint32    `json:"blocker_id" gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked"`
+	BlockedID uint32    `json:"blocked_id" gorm:"not null;uniqueIndex:idx_blocks_blocker_blocked;index"`
+}
+
+// 重复关注不会报错
+func FollowUser(followerID uint32, followeeID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Follow{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create follow failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func UnfollowUser(followerID uint32, followeeID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&Follow{})
+	if result.Error != nil {
+		logs.Warn("Delete follow failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func IsFollowing(followerID uint32, followeeID uint32) (bool, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var count int64
+	result := m.tx.Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count)
+	if result.Error != nil {
+		logs.Info("Find follow failed.", zap.Error(result.Error))
+		m.Abort()
+		return false, result.Error
+	}
+
+	m.tx.Commit()
+	return count > 0, nil
+}
+
+// 拉黑的同时解除双方的关注关系
+func BlockUser(blockerID uint32, blockedID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Block{
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create block failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	result = m.tx.Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)",
+		blockerID, blockedID, blockedID, blockerID).Delete(&Follow{})
+	if result.Error != nil {
+		logs.Warn("Delete follow for block failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func UnblockUser(blockerID uint32, blockedID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&Block{})
+	if result.Error != nil {
+		logs.Warn("Delete block failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+// 任意一方拉黑了另一方
+func IsBlockedBetween(userID uint32, otherID uint32) (bool, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var count int64
+	result := m.tx.Model(&Block{}).Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+		userID, otherID, otherID, userID).Count(&count)
+	if result.Error != nil {
+		logs.Info("Find block failed.", zap.Error(result.Error))
+		m.Abort()
+		return false, result.Error
+	}
+
+	m.tx.Commit()
+	return count > 0, nil
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+type Like struct {
+	ID        uint32    `json:"like_id"    gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint32    `json:"user_id"    gorm:"not null;uniqueIndex:idx_likes_user_post"`
+	PostID    uint32    `json:"post_id"    gorm:"not null;uniqueIndex:idx_likes_user_post;index"`
+}
+
+// 重复点赞不会报错
+func CreateLike(userID uint32, postID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Like{
+		UserID: userID,
+		PostID: postID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create like failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func DeleteLike(userID uint32, postID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("user_id = ? AND post_id = ?", userID, postID).Delete(&Like{})
+	if result.Error != nil {
+		logs.Warn("Delete like failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+// 返回 postIDs 中每个帖子的点赞数，没有点赞的帖子不在结果中
+func GetLikeCounts(postIDs []uint32) (map[uint32]int64, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var rows []struct {
+		PostID uint32
+		Count  int64
+	}
+	counts := make(map[uint32]int64)
+	result := m.tx.Model(&Like{}).Select("post_id, count(*) AS count").
+		Where("post_id IN ?", postIDs).Group("post_id").Scan(&rows)
+	if result.Error != nil {
+		logs.Info("Count likes failed.", zap.Error(result.Error))
+		m.Abort()
+		return counts, result.Error
+	}
+
+	m.tx.Commit()
+	for _, row := range rows {
+		counts[row.PostID] = row.Count
+	}
+	return counts, nil
+}
+
+// 返回 postIDs 中已被用户点赞的帖子
+func GetLikedPostIDs(userID uint32, postIDs []uint32) (map[uint32]bool, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var ids []uint32
+	liked := make(map[uint32]bool)
+	result := m.tx.Model(&Like{}).Where("user_id = ? AND post_id IN ?", userID, postIDs).Pluck("post_id", &ids)
+	if result.Error != nil {
+		logs.Info("Find liked post ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return liked, result.Error
+	}
+
+	m.tx.Commit()
+	for _, id := range ids {
+		liked[id] = true
+	}
+	return liked, nil
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"byoj/utils/content"
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	LinkPreviewPending  = "pending"
+	LinkPreviewFetching = "fetching"
+	LinkPreviewOK       = "ok"
+	LinkPreviewFailed   = "failed"
+)
+
+const (
+	// 抓取结果的缓存时间，过期后再次被帖子引用时重新抓取
+	linkPreviewTTL       = 7 * 24 * time.Hour
+	linkPreviewFailedTTL = 24 * time.Hour
+	// 抓取中的记录超过该时间仍未完成，视为抓取进程已退出，可重新领取
+	linkPreviewFetchTimeout = 10 * time.Minute
+)
+
+// 链接预览按 URL 缓存，多个帖子引用同一链接时共用
+type LinkPreview struct {
+	ID          uint32    `json:"preview_id"  gorm:"primaryKey;unique;not null"`
+	CreatedAt   time.Time `json:"created_at"`
+	URL         string    `json:"url"         gorm:"not null;uniqueIndex"`
+	Status      string    `json:"status"      gorm:"not null;index"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	ImageURL    string    `json:"image_url"`
+	SiteName    string    `json:"site_name"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// 过长的链接不生成预览
+const maxPreviewURLLength = 2048
+
+// 帖子只为第一个链接生成预览卡片，没有链接时返回空字符串
+func PreviewURL(s string) string {
+	urls := content.FindURLs(s)
+	if len(urls) == 0 || urls[0][1]-urls[0][0] > maxPreviewURLLength {
+		return ""
+	}
+	return s[urls[0][0]:urls[0][1]]
+}
+
+// 登记待抓取的链接，已缓存且未过期的链接不会重复抓取
+func queueLinkPreviews(tx *gorm.DB, urls []string, now time.Time) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	previews := make([]LinkPreview, 0, len(urls))
+	for _, u := range urls {
+		previews = append(previews, LinkPreview{URL: u, Status: LinkPreviewPending})
+	}
+	result := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "url"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"status": LinkPreviewPending,
+		}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{
+				SQL: "(link_previews.status = ? AND link_previews.fetched_at < ?) OR (link_previews.status = ? AND link_previews.fetched_at < ?)",
+				Vars: []interface{}{
+					LinkPreviewOK, now.Add(-linkPreviewTTL),
+					LinkPreviewFailed, now.Add(-linkPreviewFailedTTL),
+				},
+			},
+		}},
+	}).Create(&previews)
+	if result.Error != nil {
+		logs.Warn("Queue link previews failed.", zap.Error(result.Error))
+	}
+	return result.Error
+}
+
+/**
+ * 领取待抓取的链接并标记为抓取中，多实例同时运行时不会重复领取
+ * @param: limit 单次领取的数量
+ **/
+func ClaimLinkPreviews(now time.Time, limit int) ([]LinkPreview, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var previews []LinkPreview
+	result := m.tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? OR (status = ? AND fetched_at < ?)",
+			LinkPreviewPending, LinkPreviewFetching, now.Add(-linkPreviewFetchTimeout)).
+		Order("id").
+		Limit(limit).
+		Find(&previews)
+	if result.Error != nil {
+		logs.Warn("Find pending link previews failed.", zap.Error(result.Error))
+		m.Abort()
+		return previews, result.Error
+	}
+	if len(previews) == 0 {
+		m.tx.Commit()
+		return previews, nil
+	}
+
+	ids := make([]uint32, 0, len(previews))
+	for _, preview := range previews {
+		ids = append(ids, preview.ID)
+	}
+	result = m.tx.Model(&LinkPreview{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":     LinkPreviewFetching,
+		"fetched_at": now,
+	})
+	if result.Error != nil {
+		logs.Warn("Claim link previews failed.", zap.Error(result.Error))
+		m.Abort()
+		return previews, result.Error
+	}
+
+	m.tx.Commit()
+	return previews, nil
+}
+
+// 保存抓取结果，status 为 LinkPreviewOK 或 LinkPreviewFailed
+func SaveLinkPreview(preview LinkPreview) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Model(&LinkPreview{}).Where("id = ?", preview.ID).Updates(map[string]interface{}{
+		"status":      preview.Status,
+		"title":       preview.Title,
+		"description": preview.Description,
+		"image_url":   preview.ImageURL,
+		"site_name":   preview.SiteName,
+		"fetched_at":  preview.FetchedAt,
+	})
+	if result.Error != nil {
+		logs.Warn("Save link preview failed.", zap.Uint32("id", preview.ID), zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+// 按 URL 查询可展示的预览，重新抓取期间仍返回上次抓取的结果
+func GetLinkPreviewsByURLs(urls []string) (map[string]LinkPreview, error) {
+	mp := make(map[string]LinkPreview)
+	if len(urls) == 0 {
+		return mp, nil
+	}
+
+	m := GetModel()
+	defer m.Close()
+
+	var previews []LinkPreview
+	result := m.tx.Where("url IN ? AND title <> '' AND status IN ?", urls, []string{LinkPreviewOK, LinkPreviewPending, LinkPreviewFetching}).
+		Find(&previews)
+	if result.Error != nil {
+		logs.Info("Find link previews failed.", zap.Error(result.Error))
+		m.Abort()
+		return mp, result.Error
+	}
+
+	m.tx.Commit()
+	for _, preview := range previews {
+		mp[preview.URL] = preview
+	}
+	return mp, nil
+}
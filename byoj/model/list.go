@@ -0,0 +1,179 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const ListMemberLimit = 500
+
+type List struct {
+	ID          uint32         `json:"list_id"     form:"list_id"     query:"list_id"   gorm:"primaryKey;unique;not null"`
+	CreatedAt   time.Time      `json:"created_at"  form:"created_at"  query:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"  form:"updated_at"  query:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at"  form:"deleted_at"  query:"deleted_at"`
+	OwnerID     uint32         `json:"user_id"     form:"user_id"     query:"user_id"   gorm:"not null;index"`
+	Name        string         `json:"name"        form:"name"        query:"name"      gorm:"not null"`
+	Description string         `json:"description" form:"description" query:"description"`
+	IsPublic    bool           `json:"is_public"   form:"is_public"   query:"is_public" gorm:"not null"`
+}
+
+type ListMember struct {
+	ListID    uint32    `json:"list_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint32    `json:"user_id" gorm:"primaryKey;autoIncrement:false;index"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func CreateList(ownerID uint32, name string, description string, isPublic bool) (List, error) {
+	m := GetModel()
+	defer m.Close()
+
+	list := List{
+		OwnerID:     ownerID,
+		Name:        name,
+		Description: description,
+		IsPublic:    isPublic,
+	}
+	result := m.tx.Create(&list)
+	if result.Error != nil {
+		logs.Warn("Create list failed.", zap.Error(result.Error))
+		m.Abort()
+		return list, result.Error
+	}
+
+	m.tx.Commit()
+	return list, nil
+}
+
+func FindListByID(listID uint32) (List, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var list List
+	result := m.tx.First(&list, listID)
+	if result.Error != nil {
+		logs.Info("Find list by id failed.", zap.Error(result.Error))
+		m.Abort()
+		return list, result.Error
+	}
+
+	m.tx.Commit()
+	return list, nil
+}
+
+// onlyPublic 为 true 时只返回公开列表
+func GetListsByOwner(ownerID uint32, onlyPublic bool) ([]List, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var lists []List
+	result := m.tx.Model(&List{}).Where("owner_id = ?", ownerID)
+	if onlyPublic {
+		result = result.Where("is_public = ?", true)
+	}
+	result = result.Order("id").Find(&lists)
+	if result.Error != nil {
+		logs.Info("Find lists by owner failed.", zap.Error(result.Error))
+		m.Abort()
+		return lists, result.Error
+	}
+
+	m.tx.Commit()
+	return lists, nil
+}
+
+// 重复添加不会报错
+func AddListMember(listID uint32, userID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&ListMember{
+		ListID: listID,
+		UserID: userID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create list member failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func RemoveListMember(listID uint32, userID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("list_id = ? AND user_id = ?", listID, userID).Delete(&ListMember{})
+	if result.Error != nil {
+		logs.Warn("Delete list member failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func GetListMembers(listID uint32) ([]User, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var users []User
+	result := m.tx.Model(&User{}).
+		Joins("JOIN list_members ON list_members.user_id = users.id AND list_members.list_id = ?", listID).
+		Order("list_members.created_at").
+		Find(&users)
+	if result.Error != nil {
+		logs.Info("Find list members failed.", zap.Error(result.Error))
+		m.Abort()
+		return users, result.Error
+	}
+
+	m.tx.Commit()
+	return users, nil
+}
+
+func CountListMembers(listID uint32) (int64, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var count int64
+	result := m.tx.Model(&ListMember{}).Where("list_id = ?", listID).Count(&count)
+	if result.Error != nil {
+		logs.Info("Count list members failed.", zap.Error(result.Error))
+		m.Abort()
+		return 0, result.Error
+	}
+
+	m.tx.Commit()
+	return count, nil
+}
+
+/**
+ * 获取列表成员发表的帖子，参数含义同 GetPostsList
+ * @param: viewerID 当前用户 ID，可见其本人的非公开帖子
+ **/
+func GetListPostsList(listID uint32, viewerID uint32, startTime time.Time, orderBy string, cursor *PostCursor, limit int) ([]Post, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var posts []Post
+	result := m.tx.Model(&Post{}).
+		Where("user_id IN (?)", m.tx.Model(&ListMember{}).Select("user_id").Where("list_id = ?", listID)).
+		Where("is_public = ? OR user_id = ?", true, viewerID)
+	result = filterPostsList(result, startTime, false, orderBy, cursor, limit).Find(&posts)
+	if result.Error != nil {
+		logs.Info("Find list posts list failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
+
+	m.tx.Commit()
+	return posts, nil
+}
@@ -37,6 +37,18 @@ func InitModel() error {
 		return err
 	}
 
+	err = AutoMigrateTable(&Follow{}, &Block{}, &Suggestion{}, &UserPopularity{}, &Bookmark{}, &List{}, &ListMember{}, &Attachment{},
+		&Poll{}, &PollOption{}, &PollVote{}, &Notification{}, &ScheduledPost{},
+		&LinkPreview{}, &FilterRule{}, &MutedWord{}, &Like{})
+	if err != nil {
+		return err
+	}
+
+	err = migrateSearchIndex()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	NotificationPollClosed   = "poll_closed"
+	NotificationPostApproved = "post_approved"
+	NotificationPostRejected = "post_rejected"
+)
+
+type Notification struct {
+	ID        uint32    `json:"notification_id" gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    uint32    `json:"user_id"         gorm:"not null;index"`
+	Type      string    `json:"type"            gorm:"not null"`
+	PostID    uint32    `json:"post_id"`
+	Content   string    `json:"content"`
+	Read      bool      `json:"read"            gorm:"not null;default:false"`
+}
+
+func createNotifications(tx *gorm.DB, notifications []Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+	result := tx.Create(&notifications)
+	if result.Error != nil {
+		logs.Warn("Create notifications failed.", zap.Error(result.Error))
+	}
+	return result.Error
+}
+
+/**
+ * 获取用户的通知，按时间倒序
+ * @param: beforeID 只返回 ID 小于该值的通知，为 0 不限制
+ * @param: limit 限制结果数量
+ **/
+func GetNotifications(userID uint32, beforeID uint32, limit int) ([]Notification, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var notifications []Notification
+	result := m.tx.Model(&Notification{}).Where("user_id = ?", userID)
+	if beforeID > 0 {
+		result = result.Where("id < ?", beforeID)
+	}
+	if limit <= 0 {
+		limit = 20
+	}
+	result = result.Order("id desc").Limit(limit).Find(&notifications)
+	if result.Error != nil {
+		logs.Info("Find notifications failed.", zap.Error(result.Error))
+		m.Abort()
+		return notifications, result.Error
+	}
+
+	m.tx.Commit()
+	return notifications, nil
+}
+
+// 将 ID 不大于 untilID 的通知标记为已读
+func MarkNotificationsRead(userID uint32, untilID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Model(&Notification{}).
+		Where("user_id = ? AND id <= ? AND read = ?", userID, untilID, false).
+		Update("read", true)
+	if result.Error != nil {
+		logs.Warn("Mark notifications read failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
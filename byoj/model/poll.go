@@ -0,0 +1,269 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	PollMinOptions = 2
+	PollMaxOptions = 4
+)
+
+var (
+	ErrPollEnded     = errors.New("poll has ended")
+	ErrAlreadyVoted  = errors.New("user has already voted in this poll")
+	ErrInvalidOption = errors.New("option does not belong to this poll")
+)
+
+type Poll struct {
+	ID        uint32       `json:"poll_id"  gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time    `json:"created_at"`
+	PostID    uint32       `json:"post_id"  gorm:"not null;unique"`
+	EndTime   time.Time    `json:"end_time" gorm:"not null;index"`
+	Closed    bool         `json:"closed"   gorm:"not null;default:false"`
+	Options   []PollOption `json:"options"  gorm:"foreignKey:PollID"`
+}
+
+type PollOption struct {
+	ID       uint32 `json:"option_id" gorm:"primaryKey;unique;not null"`
+	PollID   uint32 `json:"poll_id"   gorm:"not null;index"`
+	Position int    `json:"position"  gorm:"not null"`
+	Text     string `json:"text"      gorm:"not null"`
+}
+
+// 每个用户在每个投票中只能投一次，由唯一索引保证
+type PollVote struct {
+	ID        uint32    `json:"vote_id"   gorm:"primaryKey;unique;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	PollID    uint32    `json:"poll_id"   gorm:"not null;uniqueIndex:idx_poll_votes_poll_user"`
+	UserID    uint32    `json:"user_id"   gorm:"not null;uniqueIndex:idx_poll_votes_poll_user"`
+	OptionID  uint32    `json:"option_id" gorm:"not null;index"`
+}
+
+func (p Poll) IsEnded(now time.Time) bool {
+	return p.Closed || !p.EndTime.After(now)
+}
+
+// 在帖子所在事务中创建投票及选项
+func createPoll(tx *gorm.DB, postID uint32, poll *Poll) error {
+	poll.PostID = postID
+	for i := range poll.Options {
+		poll.Options[i].Position = i
+	}
+	result := tx.Create(poll)
+	if result.Error != nil {
+		logs.Warn("Create poll failed.", zap.Error(result.Error))
+	}
+	return result.Error
+}
+
+func FindPollByPostID(postID uint32) (Poll, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var poll Poll
+	result := m.tx.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("post_id = ?", postID).First(&poll)
+	if result.Error != nil {
+		logs.Info("Find poll by post id failed.", zap.Error(result.Error))
+		m.Abort()
+		return poll, result.Error
+	}
+
+	m.tx.Commit()
+	return poll, nil
+}
+
+type PollTally struct {
+	Votes         map[uint32]int64
+	Total         int64
+	VotedOptionID uint32
+}
+
+/**
+ * 获取帖子的投票及计票结果
+ * @param: viewerID 当前用户 ID，用于判断是否已投票，为 0 不查询
+ **/
+func GetPollsByPostIDs(postIDs []uint32, viewerID uint32) (map[uint32]Poll, map[uint32]PollTally, error) {
+	m := GetModel()
+	defer m.Close()
+
+	polls := make(map[uint32]Poll)
+	tallies := make(map[uint32]PollTally)
+
+	var list []Poll
+	result := m.tx.Preload("Options", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Where("post_id IN ?", postIDs).Find(&list)
+	if result.Error != nil {
+		logs.Info("Find polls by post ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return polls, tallies, result.Error
+	}
+	if len(list) == 0 {
+		m.tx.Commit()
+		return polls, tallies, nil
+	}
+
+	pollIDs := make([]uint32, 0, len(list))
+	for _, poll := range list {
+		polls[poll.PostID] = poll
+		pollIDs = append(pollIDs, poll.ID)
+		tallies[poll.ID] = PollTally{Votes: make(map[uint32]int64)}
+	}
+
+	var counts []struct {
+		PollID   uint32
+		OptionID uint32
+		Count    int64
+	}
+	result = m.tx.Model(&PollVote{}).
+		Select("poll_id, option_id, count(*) AS count").
+		Where("poll_id IN ?", pollIDs).
+		Group("poll_id, option_id").
+		Scan(&counts)
+	if result.Error != nil {
+		logs.Info("Count poll votes failed.", zap.Error(result.Error))
+		m.Abort()
+		return polls, tallies, result.Error
+	}
+	for _, count := range counts {
+		tally := tallies[count.PollID]
+		tally.Votes[count.OptionID] = count.Count
+		tally.Total += count.Count
+		tallies[count.PollID] = tally
+	}
+
+	if viewerID != 0 {
+		var votes []PollVote
+		result = m.tx.Model(&PollVote{}).Where("poll_id IN ? AND user_id = ?", pollIDs, viewerID).Find(&votes)
+		if result.Error != nil {
+			logs.Info("Find poll votes failed.", zap.Error(result.Error))
+			m.Abort()
+			return polls, tallies, result.Error
+		}
+		for _, vote := range votes {
+			tally := tallies[vote.PollID]
+			tally.VotedOptionID = vote.OptionID
+			tallies[vote.PollID] = tally
+		}
+	}
+
+	m.tx.Commit()
+	return polls, tallies, nil
+}
+
+func VotePoll(pollID uint32, optionID uint32, userID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	var poll Poll
+	result := m.tx.First(&poll, pollID)
+	if result.Error != nil {
+		logs.Info("Find poll by id failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if poll.IsEnded(time.Now()) {
+		m.Abort()
+		return ErrPollEnded
+	}
+
+	var count int64
+	result = m.tx.Model(&PollOption{}).Where("id = ? AND poll_id = ?", optionID, pollID).Count(&count)
+	if result.Error != nil {
+		logs.Info("Find poll option failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if count == 0 {
+		m.Abort()
+		return ErrInvalidOption
+	}
+
+	result = m.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&PollVote{
+		PollID:   pollID,
+		UserID:   userID,
+		OptionID: optionID,
+	})
+	if result.Error != nil {
+		logs.Warn("Create poll vote failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return ErrAlreadyVoted
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+/**
+ * 关闭已到期的投票并通知帖子作者
+ * 关闭与通知在同一事务中完成，SKIP LOCKED 保证多实例下不会重复通知
+ **/
+func CloseDuePolls(now time.Time, limit int) (int, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var polls []Poll
+	result := m.tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("closed = ? AND end_time <= ?", false, now).
+		Order("end_time").
+		Limit(limit).
+		Find(&polls)
+	if result.Error != nil {
+		logs.Info("Find due polls failed.", zap.Error(result.Error))
+		m.Abort()
+		return 0, result.Error
+	}
+	if len(polls) == 0 {
+		m.tx.Commit()
+		return 0, nil
+	}
+
+	pollIDs := make([]uint32, 0, len(polls))
+	postIDs := make([]uint32, 0, len(polls))
+	for _, poll := range polls {
+		pollIDs = append(pollIDs, poll.ID)
+		postIDs = append(postIDs, poll.PostID)
+	}
+	result = m.tx.Model(&Poll{}).Where("id IN ?", pollIDs).Update("closed", true)
+	if result.Error != nil {
+		logs.Warn("Close polls failed.", zap.Error(result.Error))
+		m.Abort()
+		return 0, result.Error
+	}
+
+	var posts []Post
+	result = m.tx.Model(&Post{}).Where("id IN ?", postIDs).Find(&posts)
+	if result.Error != nil {
+		logs.Info("Find posts of polls failed.", zap.Error(result.Error))
+		m.Abort()
+		return 0, result.Error
+	}
+	notifications := make([]Notification, 0, len(posts))
+	for _, post := range posts {
+		notifications = append(notifications, Notification{
+			UserID: post.AuthorID,
+			Type:   NotificationPollClosed,
+			PostID: post.ID,
+		})
+	}
+	if err := createNotifications(m.tx, notifications); err != nil {
+		m.Abort()
+		return 0, err
+	}
+
+	m.tx.Commit()
+	return len(polls), nil
+}
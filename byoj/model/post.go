@@ -2,10 +2,14 @@ package model
 
 import (
 	"byoj/utils/logs"
+	"encoding/base64"
+	"strconv"
+	"strings"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Post struct {
@@ -17,6 +21,14 @@ type Post struct {
 	Time      time.Time      `json:"time"       form:"time"       query:"time"`
 	Content   string         `json:"content"    form:"content"    query:"content"`
 	IsPublic  bool           `json:"is_public"  form:"is_public"  query:"is_public" gorm:"not null"`
+	Held      bool           `json:"held"       form:"held"       query:"held"      gorm:"not null;default:false"`
+	Sensitive bool           `json:"sensitive"  form:"sensitive"  query:"sensitive" gorm:"not null;default:false"`
+
+	ContentWarning string `json:"content_warning" form:"content_warning" query:"content_warning"`
+	SensitiveMedia bool   `json:"sensitive_media" form:"sensitive_media" query:"sensitive_media" gorm:"not null;default:false"`
+
+	// 帖子对他人可见的时间：发布时间，或审核通过的时间；待审核时为零值
+	PublishedAt time.Time `json:"published_at" form:"published_at" query:"published_at" gorm:"index"`
 }
 
 func CreatePost(authorID uint32, _time time.Time, content string, isPublic bool) (Post, error) {
@@ -28,6 +40,8 @@ func CreatePost(authorID uint32, _time time.Time, content string, isPublic bool)
 		Time:     _time,
 		Content:  content,
 		IsPublic: isPublic,
+
+		PublishedAt: time.Now(),
 	}
 	result := m.tx.Create(&post)
 	if result.Error != nil {
@@ -40,6 +54,67 @@ func CreatePost(authorID uint32, _time time.Time, content string, isPublic bool)
 	return post, nil
 }
 
+/**
+ * 发布帖子，可附带媒体及投票
+ * 附件必须属于作者且未被其他帖子使用，帖子、附件与投票在同一事务中写入，任一失败均回滚
+ * @param: poll 为 nil 不创建投票
+ **/
+func PublishPost(post Post, attachmentIDs []uint32, poll *Poll) (Post, error) {
+	m := GetModel()
+	defer m.Close()
+
+	err := publishPost(m.tx, &post, attachmentIDs, poll)
+	if err != nil {
+		m.Abort()
+		return post, err
+	}
+
+	m.tx.Commit()
+	return post, nil
+}
+
+func publishPost(tx *gorm.DB, post *Post, attachmentIDs []uint32, poll *Poll) error {
+	// 命中过滤规则等待审核的帖子，审核通过前仅作者可见
+	if post.Held {
+		post.IsPublic = false
+	} else {
+		post.PublishedAt = time.Now()
+	}
+	result := tx.Create(post)
+	if result.Error != nil {
+		logs.Warn("Create post failed.", zap.Error(result.Error))
+		return result.Error
+	}
+
+	if len(attachmentIDs) > 0 {
+		result = tx.Model(&Attachment{}).
+			Where("id IN ? AND owner_id = ? AND post_id = 0", attachmentIDs, post.AuthorID).
+			Update("post_id", post.ID)
+		if result.Error != nil {
+			logs.Warn("Attach media to post failed.", zap.Error(result.Error))
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(attachmentIDs)) {
+			return ErrInvalidAttachment
+		}
+	}
+
+	if poll != nil {
+		err := createPoll(tx, post.ID, poll)
+		if err != nil {
+			return err
+		}
+	}
+
+	if u := PreviewURL(post.Content); u != "" {
+		err := queueLinkPreviews(tx, []string{u}, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func FindPostByPostID(postID uint32) (Post, error) {
 	m := GetModel()
 	defer m.Close()
@@ -56,15 +131,54 @@ func FindPostByPostID(postID uint32) (Post, error) {
 	return post, nil
 }
 
+// 按时间排序的帖子列表的游标，记录上一页最后一个帖子的发布时间与 ID
+type PostCursor struct {
+	Time time.Time
+	ID   uint32
+}
+
+func NewPostCursor(post Post) PostCursor {
+	return PostCursor{Time: post.Time, ID: post.ID}
+}
+
+func (c PostCursor) Encode() string {
+	raw := strconv.FormatInt(c.Time.UnixMicro(), 10) + ":" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodePostCursor(s string) (*PostCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micro, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	t, err := strconv.ParseInt(micro, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	i, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &PostCursor{Time: time.UnixMicro(t), ID: uint32(i)}, nil
+}
+
 /**
  * 获取帖子列表
  * @param: authorID 发表人 user_id，为 0 不限制
  * @param: startTime 帖子起始时间往前筛选
  * @param: isPublic 是否筛选 is_public 只为 true 的帖子，为 false 也会返回 is_public 为 true 的帖子
  * @param: orderBy 结果排序方式，可选："time", "random"
+ * @param: cursor 上一页游标，仅按时间排序时有效，为 nil 从头开始
  * @param: limit 限制结果数量
  **/
-func GetPostsList(authorID uint32, startTime time.Time, isPublic bool, orderBy string, limit int) ([]Post, error) {
+func GetPostsList(authorID uint32, startTime time.Time, isPublic bool, orderBy string, cursor *PostCursor, limit int) ([]Post, error) {
 	m := GetModel()
 	defer m.Close()
 
@@ -73,25 +187,80 @@ func GetPostsList(authorID uint32, startTime time.Time, isPublic bool, orderBy s
 	if authorID > 0 {
 		result = result.Where("user_id = ?", authorID)
 	}
+	result = filterPostsList(result, startTime, isPublic, orderBy, cursor, limit).Find(&posts)
+	if result.Error != nil {
+		logs.Info("Find posts list failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
+
+	m.tx.Commit()
+	return posts, nil
+}
+
+// GetPostsList 等帖子列表查询共用的筛选、排序及数量限制
+func filterPostsList(result *gorm.DB, startTime time.Time, isPublic bool, orderBy string, cursor *PostCursor, limit int) *gorm.DB {
 	if startTime != time.Unix(0, 0) {
 		result = result.Where("time <= ?", startTime)
 	}
 	if isPublic {
 		result = result.Where("is_public = ?", true)
 	}
+	result = result.Where("held = ?", false)
 	if orderBy == "time" {
-		result = result.Order("time desc")
+		// 同一时间的帖子按 ID 排序，游标才能准确定位
+		if cursor != nil {
+			result = result.Where("(time, id) < (?, ?)", cursor.Time, cursor.ID)
+		}
+		result = result.Order("time desc").Order("id desc")
 	} else {
 		result = result.Order("random()")
 	}
 	if limit <= 0 {
 		limit = 20
 	}
-	result = result.Limit(limit)
+	return result.Limit(limit)
+}
+
+// 获取待审核的帖子，按发布时间正序
+func GetHeldPosts(afterID uint32, limit int) ([]Post, error) {
+	m := GetModel()
+	defer m.Close()
+
+	if limit <= 0 {
+		limit = 20
+	}
+	var posts []Post
+	result := m.tx.Where("held = ? AND id > ?", true, afterID).Order("id").Limit(limit).Find(&posts)
+	if result.Error != nil {
+		logs.Info("Find held posts failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
 
-	result.Find(&posts)
+	m.tx.Commit()
+	return posts, nil
+}
+
+/**
+ * 按可见时间正序获取公开帖子，用于推送新帖子
+ * 以 (published_at, id) 为游标，返回在 (after, afterID) 之后的帖子
+ **/
+func GetPublicPostsPublishedAfter(after time.Time, afterID uint32, limit int) ([]Post, error) {
+	m := GetModel()
+	defer m.Close()
+
+	if limit <= 0 {
+		limit = 20
+	}
+	var posts []Post
+	result := m.tx.Where("is_public = ? AND held = ?", true, false).
+		Where("(published_at, id) > (?, ?)", after, afterID).
+		Order("published_at").Order("id").
+		Limit(limit).
+		Find(&posts)
 	if result.Error != nil {
-		logs.Info("Find posts list failed.", zap.Error(result.Error))
+		logs.Info("Find published posts failed.", zap.Error(result.Error))
 		m.Abort()
 		return posts, result.Error
 	}
@@ -99,3 +268,79 @@ func GetPostsList(authorID uint32, startTime time.Time, isPublic bool, orderBy s
 	m.tx.Commit()
 	return posts, nil
 }
+
+/**
+ * 审核帖子，通过后公开，不通过则删除，并通知作者
+ * @param: approve 是否通过
+ **/
+func ReviewHeldPost(postID uint32, approve bool) error {
+	m := GetModel()
+	defer m.Close()
+
+	var post Post
+	result := m.tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("held = ?", true).First(&post, postID)
+	if result.Error != nil {
+		logs.Info("Find held post failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	notification := Notification{UserID: post.AuthorID, PostID: post.ID}
+	if approve {
+		notification.Type = NotificationPostApproved
+		result = m.tx.Model(&post).Updates(map[string]interface{}{"held": false, "is_public": true, "published_at": time.Now()})
+	} else {
+		notification.Type = NotificationPostRejected
+		result = m.tx.Delete(&post)
+	}
+	if result.Error != nil {
+		logs.Warn("Review held post failed.", zap.Uint32("postID", postID), zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	err := createNotifications(m.tx, []Notification{notification})
+	if err != nil {
+		m.Abort()
+		return err
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+/**
+ * 管理员修改帖子的敏感标记与内容警告
+ * @param: sensitive 帖子内容是否敏感
+ * @param: sensitiveMedia 帖子媒体是否敏感
+ * @param: contentWarning 内容警告，为空字符串则清除
+ **/
+func UpdatePostSensitivity(postID uint32, sensitive *bool, sensitiveMedia *bool, contentWarning *string) error {
+	m := GetModel()
+	defer m.Close()
+
+	// 为 nil 的字段保持不变
+	updates := make(map[string]interface{})
+	if sensitive != nil {
+		updates["sensitive"] = *sensitive
+	}
+	if sensitiveMedia != nil {
+		updates["sensitive_media"] = *sensitiveMedia
+	}
+	if contentWarning != nil {
+		updates["content_warning"] = *contentWarning
+	}
+	result := m.tx.Model(&Post{}).Where("id = ?", postID).Updates(updates)
+	if result.Error != nil {
+		logs.Warn("Update post's sensitivity failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return gorm.ErrRecordNotFound
+	}
+
+	m.tx.Commit()
+	return nil
+}
@@ -0,0 +1,287 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"errors"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ScheduledStatusDraft     = "draft"
+	ScheduledStatusScheduled = "scheduled"
+	ScheduledStatusPublished = "published"
+	ScheduledStatusFailed    = "failed"
+)
+
+// 已发布或正在发布的定时帖子不能再修改
+var ErrScheduledPostNotEditable = errors.New("scheduled post is not editable")
+
+/**
+ * 草稿与定时帖子，发布时才写入 posts 表
+ * ScheduledAt 为空时为草稿
+ * Held 与 Sensitive 为创建或修改时内容策略的处理结果，发布时带入帖子
+ **/
+type ScheduledPost struct {
+	ID              uint32     `json:"scheduled_id"      gorm:"primaryKey;unique;not null"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	AuthorID        uint32     `json:"user_id"           gorm:"not null;index"`
+	Content         string     `json:"content"`
+	IsPublic        bool       `json:"is_public"         gorm:"not null"`
+	Held            bool       `json:"held"              gorm:"not null;default:false"`
+	Sensitive       bool       `json:"sensitive"         gorm:"not null;default:false"`
+	ContentWarning  string     `json:"content_warning"`
+	SensitiveMedia  bool       `json:"sensitive_media"   gorm:"not null;default:false"`
+	MediaIDs        []uint32   `json:"media_ids"         gorm:"serializer:json"`
+	PollOptions     []string   `json:"poll_options"      gorm:"serializer:json"`
+	PollEndTime     time.Time  `json:"poll_end_time"`
+	ScheduledAt     *time.Time `json:"scheduled_at"      gorm:"index"`
+	Status          string     `json:"status"            gorm:"not null;index"`
+	PublishedPostID uint32     `json:"published_post_id"`
+	FailReason      string     `json:"fail_reason"`
+}
+
+func (s ScheduledPost) poll() *Poll {
+	if len(s.PollOptions) == 0 {
+		return nil
+	}
+	poll := &Poll{EndTime: s.PollEndTime}
+	for _, text := range s.PollOptions {
+		poll.Options = append(poll.Options, PollOption{Text: text})
+	}
+	return poll
+}
+
+func CreateScheduledPost(scheduled ScheduledPost) (ScheduledPost, error) {
+	m := GetModel()
+	defer m.Close()
+
+	scheduled.Status = ScheduledStatusDraft
+	if scheduled.ScheduledAt != nil {
+		scheduled.Status = ScheduledStatusScheduled
+	}
+	result := m.tx.Create(&scheduled)
+	if result.Error != nil {
+		logs.Warn("Create scheduled post failed.", zap.Error(result.Error))
+		m.Abort()
+		return scheduled, result.Error
+	}
+
+	m.tx.Commit()
+	return scheduled, nil
+}
+
+func FindScheduledPostByID(scheduledID uint32) (ScheduledPost, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var scheduled ScheduledPost
+	result := m.tx.First(&scheduled, scheduledID)
+	if result.Error != nil {
+		logs.Info("Find scheduled post by id failed.", zap.Error(result.Error))
+		m.Abort()
+		return scheduled, result.Error
+	}
+
+	m.tx.Commit()
+	return scheduled, nil
+}
+
+// 获取用户的草稿及定时帖子，不含已发布的
+func GetScheduledPosts(authorID uint32) ([]ScheduledPost, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var list []ScheduledPost
+	result := m.tx.Model(&ScheduledPost{}).
+		Where("author_id = ? AND status <> ?", authorID, ScheduledStatusPublished).
+		Order("scheduled_at IS NULL, scheduled_at, id").
+		Find(&list)
+	if result.Error != nil {
+		logs.Info("Find scheduled posts failed.", zap.Error(result.Error))
+		m.Abort()
+		return list, result.Error
+	}
+
+	m.tx.Commit()
+	return list, nil
+}
+
+/**
+ * 修改草稿或定时帖子
+ * 发布任务持有行锁时会等待其完成，已发布的帖子返回 ErrScheduledPostNotEditable
+ **/
+func UpdateScheduledPost(scheduled ScheduledPost) (ScheduledPost, error) {
+	m := GetModel()
+	defer m.Close()
+
+	scheduled.Status = ScheduledStatusDraft
+	if scheduled.ScheduledAt != nil {
+		scheduled.Status = ScheduledStatusScheduled
+	}
+	result := m.tx.Model(&ScheduledPost{}).
+		Where("id = ? AND author_id = ? AND status <> ?", scheduled.ID, scheduled.AuthorID, ScheduledStatusPublished).
+		Select("content", "is_public", "held", "sensitive", "content_warning", "sensitive_media", "media_ids", "poll_options", "poll_end_time", "scheduled_at", "status", "fail_reason").
+		Updates(&ScheduledPost{
+			Content:        scheduled.Content,
+			IsPublic:       scheduled.IsPublic,
+			Held:           scheduled.Held,
+			Sensitive:      scheduled.Sensitive,
+			ContentWarning: scheduled.ContentWarning,
+			SensitiveMedia: scheduled.SensitiveMedia,
+			MediaIDs:       scheduled.MediaIDs,
+			PollOptions:    scheduled.PollOptions,
+			PollEndTime:    scheduled.PollEndTime,
+			ScheduledAt:    scheduled.ScheduledAt,
+			Status:         scheduled.Status,
+			FailReason:     "",
+		})
+	if result.Error != nil {
+		logs.Warn("Update scheduled post failed.", zap.Error(result.Error))
+		m.Abort()
+		return scheduled, result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return scheduled, ErrScheduledPostNotEditable
+	}
+
+	m.tx.Commit()
+	return scheduled, nil
+}
+
+func DeleteScheduledPost(scheduledID uint32, authorID uint32) error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Where("id = ? AND author_id = ? AND status <> ?", scheduledID, authorID, ScheduledStatusPublished).
+		Delete(&ScheduledPost{})
+	if result.Error != nil {
+		logs.Warn("Delete scheduled post failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return ErrScheduledPostNotEditable
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+/**
+ * 发布已到时间的定时帖子
+ * 加锁读取、创建帖子与更新状态在同一事务中完成，SKIP LOCKED 保证多实例或重启后不会重复发布
+ * 单条发布失败时回滚到保存点并标记为失败，不影响同批其他帖子
+ **/
+func PublishDueScheduledPosts(now time.Time, limit int) (int, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var list []ScheduledPost
+	result := m.tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND scheduled_at <= ?", ScheduledStatusScheduled, now).
+		Order("scheduled_at").
+		Limit(limit).
+		Find(&list)
+	if result.Error != nil {
+		logs.Info("Find due scheduled posts failed.", zap.Error(result.Error))
+		m.Abort()
+		return 0, result.Error
+	}
+
+	for _, scheduled := range list {
+		updates := map[string]interface{}{"status": ScheduledStatusPublished}
+		savePoint := "publish_" + strconv.FormatUint(uint64(scheduled.ID), 10)
+		result = m.tx.SavePoint(savePoint)
+		if result.Error != nil {
+			logs.Warn("Create save point failed.", zap.Error(result.Error))
+			m.Abort()
+			return 0, result.Error
+		}
+
+		post, err := publishScheduledPost(m.tx, scheduled, now)
+		if err != nil {
+			logs.Warn("Publish scheduled post failed.", zap.Uint32("scheduledID", scheduled.ID), zap.Error(err))
+			// 回滚失败时事务已不可用，放弃整批，下次重新发布
+			result = m.tx.RollbackTo(savePoint)
+			if result.Error != nil {
+				logs.Warn("Rollback to save point failed.", zap.Error(result.Error))
+				m.Abort()
+				return 0, result.Error
+			}
+			updates = map[string]interface{}{"status": ScheduledStatusFailed, "fail_reason": err.Error()}
+		} else {
+			updates["published_post_id"] = post.ID
+		}
+
+		result = m.tx.Model(&ScheduledPost{ID: scheduled.ID}).Updates(updates)
+		if result.Error != nil {
+			logs.Warn("Update scheduled post status failed.", zap.Error(result.Error))
+			m.Abort()
+			return 0, result.Error
+		}
+	}
+
+	m.tx.Commit()
+	return len(list), nil
+}
+
+func publishScheduledPost(tx *gorm.DB, scheduled ScheduledPost, now time.Time) (Post, error) {
+	var author User
+	result := tx.First(&author, scheduled.AuthorID)
+	if result.Error != nil {
+		return Post{}, result.Error
+	}
+	if author.Deleted || author.IsSuspended(now) {
+		return Post{}, errors.New("author is deleted or suspended")
+	}
+
+	poll := scheduled.poll()
+	if poll != nil && !poll.EndTime.After(now) {
+		return Post{}, ErrPollEnded
+	}
+
+	post := Post{
+		AuthorID:  scheduled.AuthorID,
+		Time:      now,
+		Content:   scheduled.Content,
+		IsPublic:  scheduled.IsPublic,
+		Held:      scheduled.Held,
+		Sensitive: scheduled.Sensitive,
+
+		ContentWarning: scheduled.ContentWarning,
+		SensitiveMedia: scheduled.SensitiveMedia,
+	}
+	err := publishPost(tx, &post, scheduled.MediaIDs, poll)
+	return post, err
+}
+
+// 检查附件是否属于作者且尚未被使用
+func CheckAttachmentsAvailable(authorID uint32, attachmentIDs []uint32) (bool, error) {
+	if len(attachmentIDs) == 0 {
+		return true, nil
+	}
+
+	m := GetModel()
+	defer m.Close()
+
+	var count int64
+	result := m.tx.Model(&Attachment{}).
+		Where("id IN ? AND owner_id = ? AND post_id = 0", attachmentIDs, authorID).
+		Count(&count)
+	if result.Error != nil {
+		logs.Info("Count attachments failed.", zap.Error(result.Error))
+		m.Abort()
+		return false, result.Error
+	}
+
+	m.tx.Commit()
+	return count == int64(len(attachmentIDs)), nil
+}
@@ -0,0 +1,271 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const searchConfig = "simple"
+
+// 全文检索所需的 tsvector 列及 GIN 索引，AutoMigrate 无法创建生成列，需手动迁移
+var searchMigrations = []string{
+	`ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('simple', coalesce(content, ''))) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_posts_search_vector ON posts USING GIN (search_vector)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(user_name, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(real_name, '')), 'B') ||
+			setweight(to_tsvector('simple', coalesce(bio, '')), 'C')
+		) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_users_search_vector ON users USING GIN (search_vector)`,
+}
+
+func migrateSearchIndex() error {
+	for _, sql := range searchMigrations {
+		err := db.Exec(sql).Error
+		if err != nil {
+			logs.Error("Migrate search index failed.", zap.String("sql", sql), zap.Error(err))
+			return err
+		}
+	}
+	logs.Debug("Migrate search index successfully.")
+	return nil
+}
+
+type SearchQuery struct {
+	Terms     []string
+	Phrases   []string
+	FromUsers []string
+	Hashtags  []string
+}
+
+/**
+ * 解析搜索语句
+ * 支持 "短语"、from:用户名、#话题，其余按普通关键词处理
+ **/
+func ParseSearchQuery(q string) SearchQuery {
+	query := SearchQuery{}
+	rs := []rune(q)
+	for i := 0; i < len(rs); {
+		if unicode.IsSpace(rs[i]) {
+			i++
+			continue
+		}
+		if rs[i] == '"' {
+			j := i + 1
+			for j < len(rs) && rs[j] != '"' {
+				j++
+			}
+			if phrase := strings.TrimSpace(string(rs[i+1 : j])); phrase != "" {
+				query.Phrases = append(query.Phrases, phrase)
+			}
+			i = j + 1
+			continue
+		}
+		j := i
+		for j < len(rs) && !unicode.IsSpace(rs[j]) {
+			j++
+		}
+		word := string(rs[i:j])
+		i = j
+
+		switch {
+		case strings.HasPrefix(strings.ToLower(word), "from:"):
+			if name := strings.TrimPrefix(word[len("from:"):], "@"); name != "" {
+				query.FromUsers = append(query.FromUsers, name)
+			}
+		case strings.HasPrefix(word, "#"):
+			if tag := trimToWord(word[1:]); tag != "" {
+				query.Hashtags = append(query.Hashtags, strings.ToLower(tag))
+			}
+		default:
+			query.Terms = append(query.Terms, word)
+		}
+	}
+	return query
+}
+
+func trimToWord(s string) string {
+	end := strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	if end < 0 {
+		return s
+	}
+	return s[:end]
+}
+
+func (q SearchQuery) IsEmpty() bool {
+	return len(q.Terms) == 0 && len(q.Phrases) == 0 && len(q.FromUsers) == 0 && len(q.Hashtags) == 0
+}
+
+// 拼接 tsquery 表达式，无可检索内容时返回空字符串
+func (q SearchQuery) tsquery(withHashtags bool) (string, []interface{}) {
+	var parts []string
+	var args []interface{}
+	if len(q.Terms) > 0 {
+		parts = append(parts, "plainto_tsquery('"+searchConfig+"', ?)")
+		args = append(args, strings.Join(q.Terms, " "))
+	}
+	for _, phrase := range q.Phrases {
+		parts = append(parts, "phraseto_tsquery('"+searchConfig+"', ?)")
+		args = append(args, phrase)
+	}
+	if withHashtags {
+		for _, tag := range q.Hashtags {
+			parts = append(parts, "plainto_tsquery('"+searchConfig+"', ?)")
+			args = append(args, tag)
+		}
+	}
+	if len(parts) == 0 {
+		return "", nil
+	}
+	return "(" + strings.Join(parts, " && ") + ")", args
+}
+
+// 游标记录上一页最后一条结果的相关度与 ID
+type SearchCursor struct {
+	Rank float32
+	ID   uint32
+}
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+func (c SearchCursor) Encode() string {
+	raw := strconv.FormatFloat(float64(c.Rank), 'g', -1, 32) + ":" + strconv.FormatUint(uint64(c.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeSearchCursor(s string) (*SearchCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	rank, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+	r, err := strconv.ParseFloat(rank, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	i, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &SearchCursor{Rank: float32(r), ID: uint32(i)}, nil
+}
+
+type PostSearchResult struct {
+	Post
+	Rank float32 `gorm:"column:rank"`
+}
+
+type UserSearchResult struct {
+	User
+	Rank float32 `gorm:"column:rank"`
+}
+
+func applySearchCursor(result *gorm.DB, rankExpr string, rankArgs []interface{}, idColumn string, cursor *SearchCursor) *gorm.DB {
+	if cursor == nil {
+		return result
+	}
+	args := append([]interface{}{}, rankArgs...)
+	args = append(args, cursor.Rank)
+	args = append(args, rankArgs...)
+	args = append(args, cursor.Rank, cursor.ID)
+	return result.Where(fmt.Sprintf("(%s < ?::real OR (%s = ?::real AND %s < ?))", rankExpr, rankExpr, idColumn), args...)
+}
+
+/**
+ * 全文检索帖子
+ * @param: viewerID 当前用户 ID，可见其本人的非公开帖子，为 0 仅检索公开帖子
+ * @param: cursor 上一页游标，为 nil 从头开始
+ * @param: limit 限制结果数量
+ **/
+func SearchPosts(query SearchQuery, viewerID uint32, cursor *SearchCursor, limit int) ([]PostSearchResult, error) {
+	m := GetModel()
+	defer m.Close()
+
+	rankExpr := "0::real"
+	var rankArgs []interface{}
+	result := m.tx.Model(&Post{}).
+		Joins("JOIN users ON users.id = posts.user_id AND users.deleted = ? AND users.deleted_at IS NULL", false).
+		Where("posts.is_public = ? OR posts.user_id = ?", true, viewerID)
+
+	if tsquery, args := query.tsquery(true); tsquery != "" {
+		result = result.Where("posts.search_vector @@ "+tsquery, args...)
+		rankExpr = "ts_rank(posts.search_vector, " + tsquery + ")"
+		rankArgs = args
+	}
+	if len(query.FromUsers) > 0 {
+		result = result.Where("users.user_name IN ?", query.FromUsers)
+	}
+	for _, tag := range query.Hashtags {
+		result = result.Where("posts.content ~* ?", `#`+tag+`\M`)
+	}
+	result = applySearchCursor(result, rankExpr, rankArgs, "posts.id", cursor)
+
+	if limit <= 0 {
+		limit = 20
+	}
+	var posts []PostSearchResult
+	result = result.Select("posts.*, "+rankExpr+" AS rank", rankArgs...).
+		Order("rank desc").Order("posts.id desc").
+		Limit(limit).
+		Find(&posts)
+	if result.Error != nil {
+		logs.Info("Search posts failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
+
+	m.tx.Commit()
+	return posts, nil
+}
+
+// 全文检索用户名、真实姓名及简介，from: 与 #话题 对用户检索无效
+func SearchUsers(query SearchQuery, cursor *SearchCursor, limit int) ([]UserSearchResult, error) {
+	var users []UserSearchResult
+	tsquery, args := query.tsquery(false)
+	if tsquery == "" {
+		return users, nil
+	}
+
+	m := GetModel()
+	defer m.Close()
+
+	rankExpr := "ts_rank(users.search_vector, " + tsquery + ")"
+	result := m.tx.Model(&User{}).
+		Where("users.deleted = ?", false).
+		Where("users.search_vector @@ "+tsquery, args...)
+	result = applySearchCursor(result, rankExpr, args, "users.id", cursor)
+
+	if limit <= 0 {
+		limit = 20
+	}
+	result = result.Select("users.*, "+rankExpr+" AS rank", args...).
+		Order("rank desc").Order("users.id desc").
+		Limit(limit).
+		Find(&users)
+	if result.Error != nil {
+		logs.Info("Search users failed.", zap.Error(result.Error))
+		m.Abort()
+		return users, result.Error
+	}
+
+	m.tx.Commit()
+	return users, nil
+}
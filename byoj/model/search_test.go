@@ -0,0 +1,39 @@
+package model_test
+
+import (
+	"byoj/model"
+	"reflect"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	got := model.ParseSearchQuery(`hello "good  morning" from:@ligen131 #Golang, world`)
+	want := model.SearchQuery{
+		Terms:     []string{"hello", "world"},
+		Phrases:   []string{"good  morning"},
+		FromUsers: []string{"ligen131"},
+		Hashtags:  []string{"golang"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseSearchQuery() = %+v, want %+v", got, want)
+	}
+
+	if !model.ParseSearchQuery(`  "" from: # `).IsEmpty() {
+		t.Fatal("query without searchable content should be empty")
+	}
+}
+
+func TestSearchCursor(t *testing.T) {
+	cursor := model.SearchCursor{Rank: 0.0607927, ID: 42}
+	got, err := model.DecodeSearchCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != cursor {
+		t.Fatalf("DecodeSearchCursor() = %+v, want %+v", *got, cursor)
+	}
+
+	if _, err := model.DecodeSearchCursor("not-a-cursor"); err == nil {
+		t.Fatal("expected error for invalid cursor")
+	}
+}
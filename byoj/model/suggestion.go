@@ -0,0 +1,197 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type Suggestion struct {
+	UserID      uint32    `json:"user_id"      gorm:"primaryKey;autoIncrement:false"`
+	SuggestedID uint32    `json:"suggested_id" gorm:"primaryKey;autoIncrement:false"`
+	Score       float64   `json:"score"        gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// 用户热度 ln(1 + 粉丝数)，每轮推荐计算前统一刷新一次
+type UserPopularity struct {
+	UserID uint32  `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Score  float64 `json:"score"   gorm:"not null;index"`
+}
+
+/**
+ * 候选用户得分由三部分组成：
+ * 共同关注：关注的人也关注了对方，每人 3 分
+ * 共同话题：近期双方都使用过的话题，每个 2 分
+ * 热度：user_popularities 中的得分，热度最高的 @popular 个用户也作为候选
+ * 排除本人、已关注、任意一方拉黑、已删除及封禁中的用户
+ **/
+const computeSuggestionsSQL = `
+WITH fof AS (
+	SELECT f2.followee_id AS id, count(*) * 3.0 AS score
+	FROM follows f1 JOIN follows f2 ON f2.follower_id = f1.followee_id
+	WHERE f1.follower_id = @user
+	GROUP BY f2.followee_id
+), my_tags AS (
+	SELECT DISTINCT lower(m[1]) AS tag
+	FROM posts CROSS JOIN LATERAL regexp_matches(posts.content, '#(\w+)', 'g') AS m
+	WHERE posts.user_id = @user AND posts.time >= @since AND posts.deleted_at IS NULL
+), shared AS (
+	SELECT posts.user_id AS id, count(DISTINCT lower(m[1])) * 2.0 AS score
+	FROM posts CROSS JOIN LATERAL regexp_matches(posts.content, '#(\w+)', 'g') AS m
+	WHERE posts.is_public AND posts.time >= @since AND posts.deleted_at IS NULL
+		AND lower(m[1]) IN (SELECT tag FROM my_tags)
+	GROUP BY posts.user_id
+), popular AS (
+	SELECT user_id AS id, 0 AS score
+	FROM user_popularities
+	ORDER BY score DESC, user_id
+	LIMIT @popular
+), candidates AS (
+	SELECT id, score FROM fof
+	UNION ALL SELECT id, score FROM shared
+	UNION ALL SELECT id, score FROM popular
+)
+SELECT users.id AS suggested_id, sum(candidates.score) + coalesce(max(user_popularities.score), 0) AS score
+FROM candidates
+	JOIN users ON users.id = candidates.id
+	LEFT JOIN user_popularities ON user_popularities.user_id = users.id
+WHERE users.id <> @user AND users.deleted = false AND users.deleted_at IS NULL
+	AND NOT users.suspend_permanent AND (users.suspend_until IS NULL OR users.suspend_until < @now)
+	AND NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = @user AND followee_id = users.id)
+	AND NOT EXISTS (SELECT 1 FROM blocks WHERE (blocker_id = @user AND blocked_id = users.id)
+		OR (blocker_id = users.id AND blocked_id = @user))
+GROUP BY users.id
+ORDER BY score DESC, users.id
+LIMIT @limit`
+
+// 按粉丝数重新计算所有用户的热度，在同一事务中替换，计算推荐时不会读到一半的数据
+func RefreshUserPopularity() error {
+	m := GetModel()
+	defer m.Close()
+
+	result := m.tx.Exec("DELETE FROM user_popularities")
+	if result.Error != nil {
+		logs.Warn("Delete user popularity failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	result = m.tx.Exec(`INSERT INTO user_popularities (user_id, score)
+		SELECT followee_id, ln(1 + count(*)) FROM follows GROUP BY followee_id`)
+	if result.Error != nil {
+		logs.Warn("Create user popularity failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+/**
+ * 重新计算并保存用户的推荐关注列表，热度读取 RefreshUserPopularity 的结果
+ * @param: since 共同话题只统计该时间之后的帖子
+ * @param: limit 保存的推荐数量
+ **/
+func RefreshSuggestions(userID uint32, since time.Time, limit int) error {
+	m := GetModel()
+	defer m.Close()
+
+	now := time.Now()
+	var suggestions []Suggestion
+	result := m.tx.Raw(computeSuggestionsSQL, map[string]interface{}{
+		"user":  userID,
+		"since": since,
+		"now":   now,
+		"limit": limit,
+		// 已关注、拉黑的热门用户会被排除，多取一些
+		"popular": limit * 4,
+	}).Scan(&suggestions)
+	if result.Error != nil {
+		logs.Info("Compute suggestions failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	result = m.tx.Where("user_id = ?", userID).Delete(&Suggestion{})
+	if result.Error != nil {
+		logs.Warn("Delete suggestions failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+
+	if len(suggestions) > 0 {
+		for i := range suggestions {
+			suggestions[i].UserID = userID
+			suggestions[i].CreatedAt = now
+		}
+		result = m.tx.Create(&suggestions)
+		if result.Error != nil {
+			logs.Warn("Create suggestions failed.", zap.Error(result.Error))
+			m.Abort()
+			return result.Error
+		}
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+// 读取已计算的推荐，过滤掉计算之后才关注、拉黑，或被删除、封禁的用户
+func GetSuggestedUsers(userID uint32, limit int) ([]User, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var users []User
+	result := m.tx.Model(&User{}).
+		Joins("JOIN suggestions ON suggestions.suggested_id = users.id AND suggestions.user_id = ?", userID).
+		Where("users.deleted = ?", false).
+		Where("NOT users.suspend_permanent AND (users.suspend_until IS NULL OR users.suspend_until < ?)", time.Now()).
+		Where("NOT EXISTS (SELECT 1 FROM follows WHERE follower_id = ? AND followee_id = users.id)", userID).
+		Where("NOT EXISTS (SELECT 1 FROM blocks WHERE (blocker_id = ? AND blocked_id = users.id) OR (blocker_id = users.id AND blocked_id = ?))", userID, userID).
+		Order("suggestions.score desc").Order("users.id").
+		Limit(limit).
+		Find(&users)
+	if result.Error != nil {
+		logs.Info("Find suggested users failed.", zap.Error(result.Error))
+		m.Abort()
+		return users, result.Error
+	}
+
+	m.tx.Commit()
+	return users, nil
+}
+
+/**
+ * 按 ID 分批获取近期活跃的用户：since 之后注册，或发布过帖子、关注、点赞、收藏
+ * 不包括已删除及封禁中的用户
+ * @param: afterID 只返回 ID 大于该值的用户，首批为 0
+ **/
+func GetRecentlyActiveUserIDs(since time.Time, afterID uint32, limit int) ([]uint32, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var ids []uint32
+	result := m.tx.Model(&User{}).
+		Where("id > ? AND deleted = ?", afterID, false).
+		Where("NOT suspend_permanent AND (suspend_until IS NULL OR suspend_until < ?)", time.Now()).
+		Where(`created_at >= @since
+			OR EXISTS (SELECT 1 FROM posts WHERE posts.user_id = users.id AND posts.time >= @since)
+			OR EXISTS (SELECT 1 FROM follows WHERE follows.follower_id = users.id AND follows.created_at >= @since)
+			OR EXISTS (SELECT 1 FROM likes WHERE likes.user_id = users.id AND likes.created_at >= @since)
+			OR EXISTS (SELECT 1 FROM bookmarks WHERE bookmarks.user_id = users.id AND bookmarks.created_at >= @since)`,
+			map[string]interface{}{"since": since}).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids)
+	if result.Error != nil {
+		logs.Info("Find recently active user ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return ids, result.Error
+	}
+
+	m.tx.Commit()
+	return ids, nil
+}
@@ -0,0 +1,158 @@
+package model
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type HashtagScore struct {
+	Tag   string  `json:"tag"`
+	Count int64   `json:"count"`
+	Score float64 `json:"score"`
+}
+
+type PostScore struct {
+	PostID uint32  `json:"post_id"`
+	Score  float64 `json:"score"`
+}
+
+// 按半衰期衰减的权重，age 为距 now 的秒数
+const decaySQL = "exp(-ln(2) * extract(epoch from (?::timestamptz - posts.time)) / ?)"
+
+// 统计 [since, now] 内公开帖子中的话题，按时间衰减后的热度排序
+const trendingHashtagsSQL = `
+SELECT lower(m[1]) AS tag, count(*) AS count, sum(` + decaySQL + `) AS score
+FROM posts
+	JOIN users ON users.id = posts.user_id AND users.deleted = false AND users.deleted_at IS NULL
+	CROSS JOIN LATERAL regexp_matches(posts.content, '#(\w+)', 'g') AS m
+WHERE posts.is_public AND posts.deleted_at IS NULL AND posts.time BETWEEN ? AND ?
+GROUP BY tag
+ORDER BY score DESC, tag
+LIMIT ?`
+
+// 各类互动的权重，收藏比点赞更能说明帖子的价值，投票的成本最低
+const (
+	trendingLikeWeight     = 1.0
+	trendingBookmarkWeight = 2.0
+	trendingVoteWeight     = 0.5
+	// 所含热门话题最多将热度提高的比例
+	trendingHashtagBoost = 0.5
+)
+
+/**
+ * 帖子热度为窗口内他人的点赞、收藏、投票的加权和，乘以帖子自身的时间衰减
+ * 所含热门话题只作为次要加成，最多提高 trendingHashtagBoost；没有互动的帖子不会上榜
+ **/
+const trendingPostsSQL = `
+WITH tags AS (` + trendingHashtagsSQL + `),
+interactions AS (
+	SELECT post_id, user_id, created_at, ?::float8 AS weight FROM likes
+	UNION ALL
+	SELECT post_id, user_id, created_at, ?::float8 FROM bookmarks
+	UNION ALL
+	SELECT polls.post_id, poll_votes.user_id, poll_votes.created_at, ?::float8
+	FROM poll_votes JOIN polls ON polls.id = poll_votes.poll_id
+),
+engagement AS (
+	SELECT posts.id AS post_id, sum(interactions.weight) AS weight
+	FROM interactions
+		JOIN posts ON posts.id = interactions.post_id AND posts.user_id <> interactions.user_id
+	WHERE interactions.created_at BETWEEN ? AND ?
+	GROUP BY posts.id
+),
+boost AS (
+	SELECT posts.id AS post_id, sum(tags.score) AS score
+	FROM engagement
+		JOIN posts ON posts.id = engagement.post_id
+		CROSS JOIN LATERAL (
+			SELECT DISTINCT lower(m[1]) AS tag FROM regexp_matches(posts.content, '#(\w+)', 'g') AS m
+		) AS pt
+		JOIN tags ON tags.tag = pt.tag
+	GROUP BY posts.id
+)
+SELECT posts.id AS post_id,
+	engagement.weight * (1 + ? * least(ln(1 + coalesce(boost.score, 0)), 1)) * ` + decaySQL + ` AS score
+FROM engagement
+	JOIN posts ON posts.id = engagement.post_id
+	JOIN users ON users.id = posts.user_id AND users.deleted = false AND users.deleted_at IS NULL
+	LEFT JOIN boost ON boost.post_id = posts.id
+WHERE posts.is_public AND posts.deleted_at IS NULL AND posts.time BETWEEN ? AND ?
+ORDER BY score DESC, posts.id DESC
+LIMIT ?`
+
+/**
+ * 获取热门话题
+ * @param: now 统计截止时间
+ * @param: window 统计窗口长度
+ * @param: halfLife 热度半衰期
+ * @param: limit 限制结果数量
+ **/
+func GetTrendingHashtags(now time.Time, window time.Duration, halfLife time.Duration, limit int) ([]HashtagScore, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var tags []HashtagScore
+	result := m.tx.Raw(trendingHashtagsSQL, now, halfLife.Seconds(), now.Add(-window), now, limit).Scan(&tags)
+	if result.Error != nil {
+		logs.Info("Get trending hashtags failed.", zap.Error(result.Error))
+		m.Abort()
+		return tags, result.Error
+	}
+
+	m.tx.Commit()
+	return tags, nil
+}
+
+// 获取热门帖子，tagLimit 为参与加成的热门话题数量
+func GetTrendingPosts(now time.Time, window time.Duration, halfLife time.Duration, tagLimit int, limit int) ([]PostScore, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var posts []PostScore
+	since := now.Add(-window)
+	result := m.tx.Raw(trendingPostsSQL,
+		now, halfLife.Seconds(), since, now, tagLimit,
+		trendingLikeWeight, trendingBookmarkWeight, trendingVoteWeight,
+		since, now,
+		trendingHashtagBoost, now, halfLife.Seconds(),
+		since, now, limit,
+	).Scan(&posts)
+	if result.Error != nil {
+		logs.Info("Get trending posts failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
+
+	m.tx.Commit()
+	return posts, nil
+}
+
+// 按 postIDs 的顺序返回帖子，不存在的帖子会被跳过
+func FindPostsByIDs(postIDs []uint32) ([]Post, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var posts []Post
+	result := m.tx.Model(&Post{}).Where("id IN ?", postIDs).Find(&posts)
+	if result.Error != nil {
+		logs.Info("Find posts by ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return posts, result.Error
+	}
+
+	m.tx.Commit()
+
+	mp := make(map[uint32]Post, len(posts))
+	for _, post := range posts {
+		mp[post.ID] = post
+	}
+	ordered := make([]Post, 0, len(posts))
+	for _, id := range postIDs {
+		if post, ok := mp[id]; ok {
+			ordered = append(ordered, post)
+		}
+	}
+	return ordered, nil
+}
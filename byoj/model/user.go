@@ -2,10 +2,12 @@ package model
 
 import (
 	"byoj/utils/logs"
+	"errors"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type User struct {
@@ -20,6 +22,21 @@ type User struct {
 	Bio         string         `json:"bio"        form:"bio"        query:"bio" `
 	Verified    bool           `json:"verified"   form:"verified"   query:"verified"  gorm:"not null"`
 	Deleted     bool           `json:"deleted"    form:"deleted"    query:"deleted"   gorm:"not null"`
+	IsAdmin     bool           `json:"is_admin"   form:"is_admin"   query:"is_admin"  gorm:"not null;default:false"`
+	AvatarKey   string         `json:"avatar_key" form:"avatar_key" query:"avatar_key"`
+	BannerKey   string         `json:"banner_key" form:"banner_key" query:"banner_key"`
+
+	SuspendReason    string    `json:"suspend_reason"    form:"suspend_reason"    query:"suspend_reason"`
+	SuspendUntil     time.Time `json:"suspend_until"     form:"suspend_until"     query:"suspend_until"`
+	SuspendPermanent bool      `json:"suspend_permanent" form:"suspend_permanent" query:"suspend_permanent" gorm:"not null;default:false"`
+
+	// 是否折叠带有内容警告或敏感标记的帖子
+	CollapseSensitive bool `json:"collapse_sensitive" form:"collapse_sensitive" query:"collapse_sensitive" gorm:"not null;default:true"`
+}
+
+// 用户是否处于封禁状态，永久封禁或封禁截止时间晚于 now
+func (u User) IsSuspended(now time.Time) bool {
+	return u.SuspendPermanent || u.SuspendUntil.After(now)
 }
 
 func UserRegister(userName string, email string, passwordMD5 string, realName string, bio string) error {
@@ -76,6 +93,27 @@ func FindUserByID(userID uint32) (User, error) {
 	return user, nil
 }
 
+// 批量查询用户，返回以 ID 为键的结果，不存在的用户不在结果中
+func FindUsersByIDs(userIDs []uint32) (map[uint32]User, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var users []User
+	mp := make(map[uint32]User, len(userIDs))
+	result := m.tx.Where("id IN ?", userIDs).Find(&users)
+	if result.Error != nil {
+		logs.Info("Find users by ids failed.", zap.Error(result.Error))
+		m.Abort()
+		return mp, result.Error
+	}
+
+	m.tx.Commit()
+	for _, user := range users {
+		mp[user.ID] = user
+	}
+	return mp, nil
+}
+
 func FindUserByName(userName string) (User, error) {
 	m := GetModel()
 	defer m.Close()
@@ -107,3 +145,127 @@ func FindUserByEmail(email string) (User, error) {
 	m.tx.Commit()
 	return user, nil
 }
+
+/**
+ * 封禁用户
+ * @param: reason 封禁原因
+ * @param: until 临时封禁截止时间，permanent 为 true 时忽略
+ * @param: permanent 是否永久封禁
+ **/
+func SuspendUser(userID uint32, reason string, until time.Time, permanent bool) error {
+	m := GetModel()
+	defer m.Close()
+
+	if permanent {
+		until = time.Time{}
+	}
+	result := m.tx.Model(&User{ID: userID}).Updates(map[string]interface{}{
+		"suspend_reason":    reason,
+		"suspend_until":     until,
+		"suspend_permanent": permanent,
+	})
+	if result.Error != nil {
+		logs.Warn("Update user's suspension info failed.", zap.Error(result.Error))
+		m.Abort()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return gorm.ErrRecordNotFound
+	}
+
+	m.tx.Commit()
+	return nil
+}
+
+func UnsuspendUser(userID uint32) error {
+	return SuspendUser(userID, "", time.Time{}, false)
+}
+
+const (
+	UserAvatarColumn = "avatar_key"
+	UserBannerColumn = "banner_key"
+)
+
+// 更新头像或背景图，返回旧的 key 以便清理文件
+func UpdateUserImage(userID uint32, column string, key string) (oldKey string, err error) {
+	m := GetModel()
+	defer m.Close()
+
+	var user User
+	result := m.tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID)
+	if result.Error != nil {
+		logs.Info("Find user by id failed.", zap.Error(result.Error))
+		m.Abort()
+		return "", result.Error
+	}
+	switch column {
+	case UserAvatarColumn:
+		oldKey = user.AvatarKey
+	case UserBannerColumn:
+		oldKey = user.BannerKey
+	default:
+		m.Abort()
+		return "", errors.New("unknown image column " + column)
+	}
+
+	result = m.tx.Model(&user).Update(column, key)
+	if result.Error != nil {
+		logs.Warn("Update user's image failed.", zap.Error(result.Error))
+		m.Abort()
+		return "", result.Error
+	}
+
+	m.tx.Commit()
+	return oldKey, nil
+}
+
+func UpdateUserProfile(userID uint32, realName string, bio string) (User, error) {
+	m := GetModel()
+	defer m.Close()
+
+	var user User
+	result := m.tx.Model(&user).Clauses(clause.Returning{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"real_name": realName,
+		"bio":       bio,
+	})
+	if result.Error != nil {
+		logs.Warn("Update user's profile failed.", zap.Error(result.Error))
+		m.Abort()
+		return user, result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return user, gorm.ErrRecordNotFound
+	}
+
+	m.tx.Commit()
+	return user, nil
+}
+
+// 为 nil 的设置保持不变，均为 nil 时只返回当前用户
+func UpdateUserSettings(userID uint32, collapseSensitive *bool) (User, error) {
+	if collapseSensitive == nil {
+		return FindUserByID(userID)
+	}
+
+	m := GetModel()
+	defer m.Close()
+
+	var user User
+	result := m.tx.Model(&user).Clauses(clause.Returning{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"collapse_sensitive": *collapseSensitive,
+	})
+	if result.Error != nil {
+		logs.Warn("Update user's settings failed.", zap.Error(result.Error))
+		m.Abort()
+		return user, result.Error
+	}
+	if result.RowsAffected == 0 {
+		m.Abort()
+		return user, gorm.ErrRecordNotFound
+	}
+
+	m.tx.Commit()
+	return user, nil
+}
@@ -0,0 +1,84 @@
+package policy
+
+import (
+	"byoj/model"
+	"byoj/utils/logs"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// 其他实例修改规则后，本实例最迟在该时间后重新加载
+const reloadInterval = time.Minute
+
+var (
+	mu       sync.Mutex
+	matcher  *Matcher
+	loadedAt time.Time
+)
+
+func rulesFromModel(filterRules []model.FilterRule) []Rule {
+	rules := make([]Rule, 0, len(filterRules))
+	for _, r := range filterRules {
+		rules = append(rules, Rule{ID: r.ID, Pattern: r.Pattern, IsRegex: r.IsRegex, Action: r.Action})
+	}
+	return rules
+}
+
+func load() (*Matcher, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if matcher != nil && time.Since(loadedAt) < reloadInterval {
+		return matcher, nil
+	}
+	filterRules, err := model.GetFilterRules()
+	if err != nil {
+		return nil, err
+	}
+	// 单条规则失效不应使整个过滤器失效，跳过并记录
+	rules := make([]Rule, 0, len(filterRules))
+	for _, rule := range rulesFromModel(filterRules) {
+		if _, err := CompileRule(rule); err != nil || !ValidAction(rule.Action) {
+			logs.Warn("Skip invalid filter rule.", zap.Uint32("ruleID", rule.ID), zap.Error(err))
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	m, err := Compile(rules)
+	if err != nil {
+		return nil, err
+	}
+	matcher, loadedAt = m, time.Now()
+	return matcher, nil
+}
+
+// 规则变更后调用，下次检查时重新加载
+func Invalidate() {
+	mu.Lock()
+	defer mu.Unlock()
+	matcher = nil
+}
+
+// 按管理员设置的过滤规则检查文本
+func Check(texts ...string) (Verdict, error) {
+	m, err := load()
+	if err != nil {
+		return Verdict{}, err
+	}
+	return m.Check(texts...), nil
+}
+
+// 编译用户的屏蔽词，用户没有屏蔽词时返回 nil
+func MutedMatcher(userID uint32) (*Matcher, error) {
+	words, err := model.GetMutedWords(userID)
+	if err != nil || len(words) == 0 {
+		return nil, err
+	}
+	rules := make([]Rule, 0, len(words))
+	for _, w := range words {
+		rules = append(rules, Rule{ID: w.ID, Pattern: w.Word})
+	}
+	return Compile(rules)
+}
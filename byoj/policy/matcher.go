@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// 处置方式，按严重程度从低到高排列
+const (
+	ActionNone      = ""
+	ActionSensitive = "sensitive"
+	ActionHold      = "hold"
+	ActionReject    = "reject"
+)
+
+const MaxPatternLength = 200
+
+var (
+	ErrEmptyPattern   = errors.New("empty pattern")
+	ErrPatternTooLong = errors.New("pattern is too long")
+	ErrInvalidAction  = errors.New("invalid action")
+)
+
+var severity = map[string]int{
+	ActionNone:      0,
+	ActionSensitive: 1,
+	ActionHold:      2,
+	ActionReject:    3,
+}
+
+type Rule struct {
+	ID      uint32
+	Pattern string
+	IsRegex bool
+	Action  string
+}
+
+type Verdict struct {
+	Action string
+	// 命中的规则 ID
+	RuleIDs []uint32
+}
+
+type compiledRule struct {
+	id     uint32
+	action string
+	re     *regexp.Regexp
+}
+
+type Matcher struct {
+	rules []compiledRule
+}
+
+func ValidAction(action string) bool {
+	_, ok := severity[action]
+	return ok && action != ActionNone
+}
+
+/**
+ * 编译规则
+ * 普通词语不区分大小写，以字母数字开头或结尾时按整词匹配，避免 "ass" 命中 "class"
+ * 正则使用 RE2 语法，匹配时间与文本长度成线性关系
+ **/
+func CompileRule(rule Rule) (*regexp.Regexp, error) {
+	pattern := strings.TrimSpace(rule.Pattern)
+	if pattern == "" {
+		return nil, ErrEmptyPattern
+	}
+	if utf8.RuneCountInString(pattern) > MaxPatternLength {
+		return nil, ErrPatternTooLong
+	}
+	if rule.IsRegex {
+		return regexp.Compile("(?i)" + pattern)
+	}
+
+	pattern = norm.NFC.String(pattern)
+	expr := regexp.QuoteMeta(pattern)
+	if first, _ := utf8.DecodeRuneInString(pattern); isASCIIWord(first) {
+		expr = `\b` + expr
+	}
+	if last, _ := utf8.DecodeLastRuneInString(pattern); isASCIIWord(last) {
+		expr = expr + `\b`
+	}
+	return regexp.Compile("(?i)" + expr)
+}
+
+func isASCIIWord(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// 编译一组规则，任一规则无效时返回错误
+func Compile(rules []Rule) (*Matcher, error) {
+	matcher := &Matcher{rules: make([]compiledRule, 0, len(rules))}
+	for _, rule := range rules {
+		if rule.Action != ActionNone && !ValidAction(rule.Action) {
+			return nil, ErrInvalidAction
+		}
+		re, err := CompileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		matcher.rules = append(matcher.rules, compiledRule{id: rule.ID, action: rule.Action, re: re})
+	}
+	return matcher, nil
+}
+
+// 检查文本，命中多条规则时取最严重的处置方式
+func (m *Matcher) Check(texts ...string) Verdict {
+	verdict := Verdict{}
+	if m == nil {
+		return verdict
+	}
+	normalized := make([]string, 0, len(texts))
+	for _, text := range texts {
+		normalized = append(normalized, norm.NFC.String(text))
+	}
+	for _, rule := range m.rules {
+		for _, text := range normalized {
+			if rule.re.MatchString(text) {
+				verdict.RuleIDs = append(verdict.RuleIDs, rule.id)
+				if severity[rule.action] > severity[verdict.Action] {
+					verdict.Action = rule.action
+				}
+				break
+			}
+		}
+	}
+	return verdict
+}
+
+// 文本是否命中任一规则
+func (m *Matcher) Matches(text string) bool {
+	if m == nil {
+		return false
+	}
+	text = norm.NFC.String(text)
+	for _, rule := range m.rules {
+		if rule.re.MatchString(text) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package policy_test
+
+import (
+	"byoj/policy"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatcherCheck(t *testing.T) {
+	m, err := policy.Compile([]policy.Rule{
+		{ID: 1, Pattern: "spoiler", Action: policy.ActionSensitive},
+		{ID: 2, Pattern: "ass", Action: policy.ActionHold},
+		{ID: 3, Pattern: "广告", Action: policy.ActionHold},
+		{ID: 4, Pattern: `buy\s+now\s+\d+%`, IsRegex: true, Action: policy.ActionReject},
+		{ID: 5, Pattern: "c++", Action: policy.ActionSensitive},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		texts  []string
+		action string
+		ids    []uint32
+	}{
+		{[]string{"hello world"}, policy.ActionNone, nil},
+		{[]string{"Major SPOILER ahead"}, policy.ActionSensitive, []uint32{1}},
+		{[]string{"spoilers"}, policy.ActionNone, nil},
+		{[]string{"first class service"}, policy.ActionNone, nil},
+		{[]string{"kick ass"}, policy.ActionHold, []uint32{2}},
+		{[]string{"这是一条广告信息"}, policy.ActionHold, []uint32{3}},
+		{[]string{"spoiler: BUY NOW 50% off"}, policy.ActionReject, []uint32{1, 4}},
+		{[]string{"I like C++!"}, policy.ActionSensitive, []uint32{5}},
+		{[]string{"clean", "kick ass"}, policy.ActionHold, []uint32{2}},
+	}
+	for _, tt := range tests {
+		v := m.Check(tt.texts...)
+		if v.Action != tt.action || !reflect.DeepEqual(v.RuleIDs, tt.ids) {
+			t.Errorf("Check(%q) = %+v, want action %q ids %v", tt.texts, v, tt.action, tt.ids)
+		}
+	}
+}
+
+func TestMatcherNormalization(t *testing.T) {
+	// 组合字符与预组合字符视为相同
+	m, err := policy.Compile([]policy.Rule{{ID: 1, Pattern: "caf\u00e9", Action: policy.ActionHold}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.Matches("CAFE\u0301 open") {
+		t.Error("decomposed text should match precomposed pattern")
+	}
+
+	var nilMatcher *policy.Matcher
+	if nilMatcher.Matches("anything") || nilMatcher.Check("anything").Action != policy.ActionNone {
+		t.Error("nil matcher should match nothing")
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	tests := []struct {
+		rule policy.Rule
+		err  error
+	}{
+		{policy.Rule{Pattern: "  ", Action: policy.ActionHold}, policy.ErrEmptyPattern},
+		{policy.Rule{Pattern: strings.Repeat("a", policy.MaxPatternLength+1), Action: policy.ActionHold}, policy.ErrPatternTooLong},
+		{policy.Rule{Pattern: "word", Action: "delete"}, policy.ErrInvalidAction},
+	}
+	for _, tt := range tests {
+		if _, err := policy.Compile([]policy.Rule{tt.rule}); err != tt.err {
+			t.Errorf("Compile(%+v) error = %v, want %v", tt.rule, err, tt.err)
+		}
+	}
+	if _, err := policy.Compile([]policy.Rule{{Pattern: "(", IsRegex: true, Action: policy.ActionHold}}); err == nil {
+		t.Error("invalid regex should fail to compile")
+	}
+}
@@ -0,0 +1,303 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	userAgent            = "ByitterBot/1.0 (+link preview)"
+)
+
+type Preview struct {
+	// 单次抓取的超时时间，单位秒
+	Timeout int `yaml:"timeout"`
+	// 读取响应体的字节数上限，超过部分直接丢弃
+	MaxBytes     int64 `yaml:"max-bytes"`
+	MaxRedirects int   `yaml:"max-redirects"`
+	// 允许访问内网地址，仅用于本地开发与测试
+	AllowPrivate bool `yaml:"allow-private"`
+}
+
+var (
+	ErrInvalidURL       = errors.New("invalid preview url")
+	ErrForbiddenAddress = errors.New("address is not allowed")
+	ErrTooManyRedirects = errors.New("too many redirects")
+	ErrBadStatus        = errors.New("unexpected response status")
+	ErrNotHTML          = errors.New("response is not html")
+	ErrNoMetadata       = errors.New("no preview metadata found")
+)
+
+type Card struct {
+	URL         string
+	Title       string
+	Description string
+	ImageURL    string
+	SiteName    string
+}
+
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+var fetcher = NewFetcher(Preview{})
+
+func InitPreview(p Preview) error {
+	fetcher = NewFetcher(p)
+	return nil
+}
+
+func NewFetcher(p Preview) *Fetcher {
+	if p.Timeout <= 0 {
+		p.Timeout = 5
+	}
+	if p.MaxBytes <= 0 {
+		p.MaxBytes = 1 << 20
+	}
+	if p.MaxRedirects <= 0 {
+		p.MaxRedirects = 3
+	}
+
+	dialer := &net.Dialer{Timeout: time.Duration(p.Timeout) * time.Second}
+	if !p.AllowPrivate {
+		// 在 DNS 解析之后、建立连接之前检查实际地址，防止通过域名或重定向访问内网
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !isPublicIP(ip) {
+				return ErrForbiddenAddress
+			}
+			return nil
+		}
+	}
+	maxRedirects := p.MaxRedirects
+	return &Fetcher{
+		client: &http.Client{
+			Timeout: time.Duration(p.Timeout) * time.Second,
+			Transport: &http.Transport{
+				// 不使用环境变量中的代理，否则地址检查只会作用于代理本身
+				Proxy:                 nil,
+				DialContext:           dialer.DialContext,
+				TLSHandshakeTimeout:   time.Duration(p.Timeout) * time.Second,
+				ResponseHeaderTimeout: time.Duration(p.Timeout) * time.Second,
+				MaxIdleConns:          10,
+				IdleConnTimeout:       30 * time.Second,
+			},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkURL(req.URL)
+			},
+		},
+		maxBytes: p.MaxBytes,
+	}
+}
+
+func Fetch(ctx context.Context, rawURL string) (Card, error) {
+	return fetcher.Fetch(ctx, rawURL)
+}
+
+/**
+ * 抓取网页并解析 Open Graph / Twitter Card / <title> 等元数据
+ * @param: rawURL 仅支持 http 与 https
+ **/
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Card, error) {
+	card := Card{URL: rawURL}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return card, ErrInvalidURL
+	}
+	if err = checkURL(u); err != nil {
+		return card, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return card, ErrInvalidURL
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && errors.Is(opErr.Err, ErrForbiddenAddress) {
+			err = ErrForbiddenAddress
+		}
+		return card, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return card, ErrBadStatus
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return card, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return card, err
+	}
+	card = parseCard(body, resp.Request.URL)
+	card.URL = rawURL
+	if card.Title == "" && card.Description == "" {
+		return card, ErrNoMetadata
+	}
+	return card, nil
+}
+
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrInvalidURL
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+var nonPublicNets = func() []*net.IPNet {
+	var nets []*net.IPNet
+	for _, cidr := range []string{
+		"0.0.0.0/8",
+		"100.64.0.0/10",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"240.0.0.0/4",
+		"64:ff9b::/96",
+		"2001:db8::/32",
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		nets = append(nets, n)
+	}
+	return nets
+}()
+
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// 依次取 og:*、twitter:*、<meta name="description"> 与 <title>，先出现的优先
+func parseCard(r io.Reader, base *url.URL) Card {
+	meta := make(map[string]string)
+	var title strings.Builder
+	inTitle := false
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return buildCard(meta, title.String(), base)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			switch string(name) {
+			case "meta":
+				var key, value string
+				for hasAttr {
+					var k, v []byte
+					k, v, hasAttr = z.TagAttr()
+					switch string(k) {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(string(v)))
+					case "content":
+						value = string(v)
+					}
+				}
+				if _, ok := meta[key]; key != "" && !ok {
+					meta[key] = value
+				}
+			case "title":
+				inTitle = tt == html.StartTagToken && title.Len() == 0
+			case "body":
+				// 元数据都在 <head> 中，无需继续解析正文
+				return buildCard(meta, title.String(), base)
+			}
+		case html.TextToken:
+			if inTitle {
+				title.Write(z.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "title" {
+				inTitle = false
+			}
+		}
+	}
+}
+
+func buildCard(meta map[string]string, title string, base *url.URL) Card {
+	first := func(keys ...string) string {
+		for _, k := range keys {
+			if v := cleanText(meta[k]); v != "" {
+				return v
+			}
+		}
+		return ""
+	}
+	card := Card{
+		Title:       truncate(first("og:title", "twitter:title"), maxTitleLength),
+		Description: truncate(first("og:description", "twitter:description", "description"), maxDescriptionLength),
+		SiteName:    truncate(first("og:site_name"), maxTitleLength),
+	}
+	if card.Title == "" {
+		card.Title = truncate(cleanText(title), maxTitleLength)
+	}
+	if image := first("og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src"); image != "" {
+		if u, err := base.Parse(image); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+			card.ImageURL = u.String()
+		}
+	}
+	return card
+}
+
+func cleanText(s string) string {
+	return strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
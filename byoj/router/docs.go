@@ -0,0 +1,124 @@
+package router
+
+import (
+	"byoj/controllers"
+	"byoj/docs"
+	"byoj/model"
+)
+
+var apiInfo = docs.Info{
+	Title:       "Byitter API",
+	Description: "All JSON responses are wrapped in {code, msg, data}; the schemas below describe data. Errors carry a stable string code in data.code and the request ID in data.request_id. Messages are localized by Accept-Language (en, zh).",
+	Version:     "1.0.0",
+}
+
+var (
+	cursorParam = docs.Param{Name: "cursor", Description: "Cursor returned as next_cursor by the previous page."}
+	limitParam  = docs.Param{Name: "limit", Type: "integer", Description: "Maximum number of results. Posts matching the viewer's muted words are removed after paging, so a page can be shorter; keep paging while next_cursor is set."}
+)
+
+// 路由对应的接口文档，新增路由时需在此补充，否则 /openapi.json 会返回错误
+var operations = []docs.Operation{
+	{Handler: controllers.IndexGET, Summary: "Links to the API documentation."},
+	{Handler: controllers.HealthGET, Summary: "Health check.", Response: ""},
+	{
+		Handler: controllers.SearchGET, Summary: "Full-text search for posts or users.",
+		Description: `Supports "phrases", from:user_name and #hashtag.`,
+		Query: []docs.Param{
+			{Name: "q", Required: true, Description: "Search query."},
+			{Name: "type", Description: "post (default) or user."},
+			cursorParam, limitParam,
+		},
+		Response: controllers.SearchResponse{},
+	},
+	{
+		Handler: controllers.TrendingGET, Summary: "Trending hashtags and posts.",
+		Query:    []docs.Param{{Name: "window", Description: "1h or 24h (default)."}},
+		Response: controllers.TrendingGetResponse{},
+	},
+
+	{
+		Handler: controllers.GraphQLPOST, Summary: "GraphQL endpoint for users, posts, timelines, posting, likes and follows.",
+		Description: "Responses follow the GraphQL specification instead of {code, msg, data}; errors carry the REST error code in extensions.code. Send an access token to act as a user.",
+		Request:     controllers.GraphQLRequest{}, Response: controllers.GraphQLResponse{}, Raw: true,
+	},
+
+	{Handler: controllers.UserGET, Summary: "Get a user by user_id, user_name or email.", Request: controllers.UserGetRequest{}, Response: controllers.UserGETResponse{}},
+	{Handler: controllers.UserRegisterPOST, Summary: "Register a new user.", Request: controllers.UserRegisterRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserLoginPOST, Summary: "Log in and get tokens.", Request: model.User{}, Response: controllers.UserLoginResponse{}},
+	{Handler: controllers.UserRefreshPOST, Summary: "Exchange a refresh token for new tokens.", Request: controllers.UserRefreshRequest{}, Response: controllers.UserLoginResponse{}},
+	{Handler: controllers.UserIsAuthGET, Summary: "Check whether the access token is valid.", Auth: true, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserAvatarPOST, Summary: "Upload an avatar.", Auth: true, Upload: "file", Response: map[string]string{}},
+	{
+		Handler: controllers.UserAvatarGET, Summary: "Get a user's avatar.", Binary: true,
+		Query: []docs.Param{{Name: "size", Description: "small, medium or large."}, {Name: "v", Description: "Cache-busting version."}},
+	},
+	{Handler: controllers.UserBannerPOST, Summary: "Upload a profile banner.", Auth: true, Upload: "file", Response: map[string]string{}},
+	{
+		Handler: controllers.UserBannerGET, Summary: "Get a user's profile banner.", Binary: true,
+		Query: []docs.Param{{Name: "size", Description: "small or large."}, {Name: "v", Description: "Cache-busting version."}},
+	},
+	{Handler: controllers.UserFollowPOST, Summary: "Follow a user.", Auth: true, Request: model.User{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserUnfollowPOST, Summary: "Unfollow a user.", Auth: true, Request: model.User{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserBlockPOST, Summary: "Block a user.", Auth: true, Request: model.User{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserUnblockPOST, Summary: "Unblock a user.", Auth: true, Request: model.User{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserSuggestionsGET, Summary: "Who to follow.", Auth: true, Response: controllers.UserSuggestionsResponse{}},
+	{
+		Handler: controllers.UserNotificationsGET, Summary: "List notifications.", Auth: true,
+		Query: []docs.Param{cursorParam, limitParam}, Response: controllers.NotificationGetResponse{},
+	},
+	{Handler: controllers.UserNotificationsReadPOST, Summary: "Mark notifications as read.", Auth: true, Request: controllers.NotificationReadRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserProfilePOST, Summary: "Update real name and bio.", Auth: true, Request: controllers.UserProfileRequest{}, Response: controllers.UserGETResponse{}},
+	{Handler: controllers.UserSettingsGET, Summary: "Get settings.", Auth: true, Response: controllers.UserSettings{}},
+	{Handler: controllers.UserSettingsPOST, Summary: "Update settings.", Auth: true, Request: controllers.UserSettingsRequest{}, Response: controllers.UserSettings{}},
+	{Handler: controllers.UserMutedPOST, Summary: "Mute a word.", Auth: true, Request: controllers.MutedWordRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserMutedGET, Summary: "List muted words.", Auth: true, Response: controllers.MutedWordsGetResponse{}},
+	{Handler: controllers.UserMutedRemovePOST, Summary: "Unmute a word.", Auth: true, Request: controllers.MutedWordRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserSuspendPOST, Summary: "Suspend a user.", Admin: true, Request: controllers.UserSuspendRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.UserUnsuspendPOST, Summary: "Lift a suspension.", Admin: true, Request: controllers.UserUnsuspendRequest{}, Response: controllers.StatusMessage{}},
+
+	{Handler: controllers.AdminFilterPOST, Summary: "Create a filter rule.", Admin: true, Request: controllers.FilterRuleRequest{}, Response: model.FilterRule{}},
+	{Handler: controllers.AdminFiltersGET, Summary: "List filter rules.", Admin: true, Response: controllers.FilterRulesGetResponse{}},
+	{Handler: controllers.AdminFilterRemovePOST, Summary: "Remove a filter rule.", Admin: true, Request: controllers.FilterRuleRequest{}, Response: controllers.StatusMessage{}},
+	{
+		Handler: controllers.AdminHeldGET, Summary: "List posts held for review.", Admin: true,
+		Query: []docs.Param{cursorParam, limitParam}, Response: controllers.HeldPostsGetResponse{},
+	},
+	{Handler: controllers.AdminHeldReviewPOST, Summary: "Approve or reject a held post.", Admin: true, Request: controllers.HeldPostReviewRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.AdminPostSensitivePOST, Summary: "Set sensitive flags and content warning of a post.", Admin: true, Request: controllers.PostSensitivityRequest{}, Response: controllers.StatusMessage{}},
+
+	{Handler: controllers.PostPOST, Summary: "Publish a post.", Auth: true, Request: controllers.PostCreateRequest{}, Response: controllers.PostCreateResponse{}},
+	{Handler: controllers.PostGET, Summary: "List posts.", Request: controllers.PostGetRequest{}, Response: controllers.PostGetResponse{}},
+	{Handler: controllers.PostScheduledPOST, Summary: "Create a draft or scheduled post.", Auth: true, Request: controllers.ScheduledPostRequest{}, Response: controllers.ScheduledPostResponse{}},
+	{Handler: controllers.PostScheduledGET, Summary: "List drafts and scheduled posts.", Auth: true, Response: controllers.ScheduledPostGetResponse{}},
+	{Handler: controllers.PostScheduledEditPOST, Summary: "Edit a draft or scheduled post.", Auth: true, Request: controllers.ScheduledPostRequest{}, Response: controllers.ScheduledPostResponse{}},
+	{Handler: controllers.PostScheduledCancelPOST, Summary: "Delete a draft or scheduled post.", Auth: true, Request: controllers.ScheduledPostRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.PostVotePOST, Summary: "Vote in a poll.", Auth: true, Request: controllers.PollVoteRequest{}, Response: controllers.PollResponse{}},
+	{Handler: controllers.PostBookmarkPOST, Summary: "Bookmark a post.", Auth: true, Request: controllers.BookmarkRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.PostUnbookmarkPOST, Summary: "Remove a bookmark.", Auth: true, Request: controllers.BookmarkRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.PostLikePOST, Summary: "Like a post.", Auth: true, Request: controllers.LikeRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.PostUnlikePOST, Summary: "Remove a like.", Auth: true, Request: controllers.LikeRequest{}, Response: controllers.StatusMessage{}},
+	{
+		Handler: controllers.PostBookmarksGET, Summary: "List bookmarked posts.", Auth: true,
+		Query: []docs.Param{cursorParam, limitParam}, Response: controllers.BookmarkGetResponse{},
+	},
+
+	{Handler: controllers.MediaPOST, Summary: "Upload an image or video.", Auth: true, Upload: "file", Response: controllers.MediaResponse{}},
+	{Handler: controllers.MediaGET, Summary: "Get uploaded media.", Binary: true},
+
+	{Handler: controllers.ListPOST, Summary: "Create a list.", Auth: true, Request: controllers.ListCreateRequest{}, Response: controllers.ListResponse{}},
+	{
+		Handler: controllers.ListGET, Summary: "Get a list and its members.",
+		Query: []docs.Param{{Name: "list_id", Type: "integer", Required: true}}, Response: controllers.ListGetResponse{},
+	},
+	{
+		Handler: controllers.ListsGET, Summary: "Lists owned by a user.",
+		Query: []docs.Param{{Name: "user_id", Type: "integer", Required: true}}, Response: controllers.ListsGetResponse{},
+	},
+	{Handler: controllers.ListAddPOST, Summary: "Add a member to a list.", Auth: true, Request: controllers.ListMemberRequest{}, Response: controllers.StatusMessage{}},
+	{Handler: controllers.ListRemovePOST, Summary: "Remove a member from a list.", Auth: true, Request: controllers.ListMemberRequest{}, Response: controllers.StatusMessage{}},
+	{
+		Handler: controllers.ListTimelineGET, Summary: "Posts from members of a list.",
+		Request: controllers.ListTimelineGetRequest{}, Response: controllers.PostGetResponse{},
+	},
+}
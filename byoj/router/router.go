@@ -3,36 +3,127 @@ package router
 import (
 	"byoj/controllers"
 	"byoj/controllers/middleware"
+	"byoj/docs"
+	"time"
 
 	"github.com/labstack/echo"
 	echomw "github.com/labstack/echo/middleware"
 )
 
+// 无版本前缀的旧路径自 legacyDeprecatedAt 起弃用，作为 /v1 的别名保留到 legacySunset
+var (
+	legacyDeprecatedAt = time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	legacySunset       = time.Date(2027, 4, 30, 0, 0, 0, 0, time.UTC)
+)
+
 func Load(e *echo.Echo) {
 	routes(e)
 }
 
 func routes(e *echo.Echo) {
+	e.HTTPErrorHandler = controllers.HTTPErrorHandler
+	e.Use(middleware.RequestIDMiddleware)
+	e.Use(middleware.LocaleMiddleware)
 	e.Use(echomw.Recover())
 
 	e.GET("/", controllers.IndexGET)
 
 	e.GET("/health", controllers.HealthGET)
 
-	userGroup := e.Group("/user")
+	// 版本中替换处理函数需在 derive 之前完成
+	v1 := newAPIVersion("/v1")
+	legacy := v1.derive("", deprecated(legacyDeprecatedAt, legacySunset, v1.prefix))
+	apiRoutes(v1.mount(e))
+	apiRoutes(legacy.mount(e))
+
+	// 文档只列出当前版本的路径
+	e.GET("/openapi.json", docs.SpecHandler(func() []*echo.Route {
+		return legacy.exclude(e.Routes())
+	}, operations, apiInfo))
+
+	e.GET("/docs", docs.UIHandler)
+}
+
+func apiRoutes(api apiGroup) {
+	api.GET("/search", controllers.SearchGET)
+
+	api.GET("/trending", controllers.TrendingGET)
+	api.POST("/graphql", controllers.GraphQLPOST)
+
+	userGroup := api.Group("/user")
 	{
 		userGroup.GET("", controllers.UserGET)
 		userGroup.GET("/", controllers.UserGET)
+		userGroup.GET("/:user_id", controllers.UserGET)
 		userGroup.POST("/register", controllers.UserRegisterPOST)
 		userGroup.POST("/login", controllers.UserLoginPOST)
+		userGroup.POST("/refresh", controllers.UserRefreshPOST)
 		userGroup.GET("/isauth", controllers.UserIsAuthGET, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/avatar", controllers.UserAvatarPOST, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/avatar/:id", controllers.UserAvatarGET)
+		userGroup.POST("/banner", controllers.UserBannerPOST, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/banner/:id", controllers.UserBannerGET)
+		userGroup.POST("/follow", controllers.UserFollowPOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/unfollow", controllers.UserUnfollowPOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/block", controllers.UserBlockPOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/unblock", controllers.UserUnblockPOST, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/suggestions", controllers.UserSuggestionsGET, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/notifications", controllers.UserNotificationsGET, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/notifications/read", controllers.UserNotificationsReadPOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/profile", controllers.UserProfilePOST, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/settings", controllers.UserSettingsGET, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/settings", controllers.UserSettingsPOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/muted", controllers.UserMutedPOST, middleware.TokenVerificationMiddleware)
+		userGroup.GET("/muted", controllers.UserMutedGET, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/muted/remove", controllers.UserMutedRemovePOST, middleware.TokenVerificationMiddleware)
+		userGroup.POST("/suspend", controllers.UserSuspendPOST, middleware.TokenVerificationMiddleware, middleware.AdminVerificationMiddleware)
+		userGroup.POST("/unsuspend", controllers.UserUnsuspendPOST, middleware.TokenVerificationMiddleware, middleware.AdminVerificationMiddleware)
 	}
 
-	postGroup := e.Group("/post")
+	adminGroup := api.Group("/admin", middleware.TokenVerificationMiddleware, middleware.AdminVerificationMiddleware)
+	{
+		adminGroup.POST("/filter", controllers.AdminFilterPOST)
+		adminGroup.GET("/filter", controllers.AdminFiltersGET)
+		adminGroup.POST("/filter/remove", controllers.AdminFilterRemovePOST)
+		adminGroup.GET("/held", controllers.AdminHeldGET)
+		adminGroup.POST("/held/review", controllers.AdminHeldReviewPOST)
+		adminGroup.POST("/post/sensitive", controllers.AdminPostSensitivePOST)
+	}
+
+	postGroup := api.Group("/post")
 	{
 		postGroup.POST("", controllers.PostPOST, middleware.TokenVerificationMiddleware)
 		postGroup.POST("/", controllers.PostPOST, middleware.TokenVerificationMiddleware)
 		postGroup.GET("", controllers.PostGET)
 		postGroup.GET("/", controllers.PostGET)
+		postGroup.POST("/scheduled", controllers.PostScheduledPOST, middleware.TokenVerificationMiddleware)
+		postGroup.GET("/scheduled", controllers.PostScheduledGET, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/scheduled/edit", controllers.PostScheduledEditPOST, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/scheduled/cancel", controllers.PostScheduledCancelPOST, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/vote", controllers.PostVotePOST, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/bookmark", controllers.PostBookmarkPOST, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/unbookmark", controllers.PostUnbookmarkPOST, middleware.TokenVerificationMiddleware)
+		postGroup.GET("/bookmarks", controllers.PostBookmarksGET, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/like", controllers.PostLikePOST, middleware.TokenVerificationMiddleware)
+		postGroup.POST("/unlike", controllers.PostUnlikePOST, middleware.TokenVerificationMiddleware)
+	}
+
+	mediaGroup := api.Group("/media")
+	{
+		mediaGroup.POST("", controllers.MediaPOST, middleware.TokenVerificationMiddleware)
+		mediaGroup.POST("/", controllers.MediaPOST, middleware.TokenVerificationMiddleware)
+		mediaGroup.GET("/:id", controllers.MediaGET)
+	}
+
+	listGroup := api.Group("/list")
+	{
+		listGroup.POST("", controllers.ListPOST, middleware.TokenVerificationMiddleware)
+		listGroup.POST("/", controllers.ListPOST, middleware.TokenVerificationMiddleware)
+		listGroup.GET("", controllers.ListGET)
+		listGroup.GET("/", controllers.ListGET)
+		listGroup.GET("/user", controllers.ListsGET)
+		listGroup.POST("/add", controllers.ListAddPOST, middleware.TokenVerificationMiddleware)
+		listGroup.POST("/remove", controllers.ListRemovePOST, middleware.TokenVerificationMiddleware)
+		listGroup.GET("/timeline", controllers.ListTimelineGET)
 	}
 }
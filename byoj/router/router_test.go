@@ -0,0 +1,476 @@
+package router_test
+
+import (
+	"byoj/controllers/auth"
+	"byoj/model"
+	"byoj/router"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo"
+)
+
+type openAPISpec struct {
+	Paths map[string]map[string]struct {
+		OperationID string `json:"operationId"`
+		RequestBody *struct {
+			Content map[string]struct {
+				Schema struct {
+					Ref string `json:"$ref"`
+				} `json:"schema"`
+			} `json:"content"`
+		} `json:"requestBody"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func loadSpec(t *testing.T, e *echo.Echo) openAPISpec {
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	// 路由与文档不一致时 /openapi.json 返回 500 并列出差异
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /openapi.json = %d: %s", rec.Code, rec.Body.String())
+	}
+	var spec openAPISpec
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+	spec := loadSpec(t, e)
+
+	for _, route := range e.Routes() {
+		if !strings.HasPrefix(route.Name, "byoj/controllers.") {
+			continue
+		}
+		path := route.Path
+		if trimmed := strings.TrimSuffix(path, "/"); trimmed != "" {
+			path = trimmed
+		}
+		segs := strings.Split(path, "/")
+		for i, seg := range segs {
+			if strings.HasPrefix(seg, ":") {
+				segs[i] = "{" + seg[1:] + "}"
+			}
+		}
+		path = strings.Join(segs, "/")
+		// 旧路径是 /v1 的别名，文档中只有 /v1 的路径
+		_, ok := spec.Paths[path][strings.ToLower(route.Method)]
+		if !ok {
+			_, ok = spec.Paths["/v1"+path][strings.ToLower(route.Method)]
+		}
+		if !ok {
+			t.Errorf("route %s %s is missing from the spec", route.Method, route.Path)
+		}
+	}
+	ids := make(map[string]string)
+	for path, methods := range spec.Paths {
+		if path != "/" && path != "/health" && !strings.HasPrefix(path, "/v1/") {
+			t.Errorf("unversioned path %s should not be in the spec", path)
+		}
+		// OpenAPI 要求 operationId 唯一
+		for method, op := range methods {
+			if other, ok := ids[op.OperationID]; ok {
+				t.Errorf("operationId %s is used by both %s and %s %s", op.OperationID, other, method, path)
+			}
+			ids[op.OperationID] = method + " " + path
+		}
+	}
+	if op := spec.Paths["/v1/user/{user_id}"]["get"]; op.OperationID != "UserGETByUserId" {
+		t.Errorf("GET /v1/user/{user_id} operationId = %q", op.OperationID)
+	}
+
+	op := spec.Paths["/v1/post"]["post"]
+	if op.RequestBody == nil || op.RequestBody.Content["application/json"].Schema.Ref != "#/components/schemas/PostCreateRequest" {
+		t.Fatalf("POST /post request body = %+v", op.RequestBody)
+	}
+	for _, field := range []string{"content", "media_ids", "poll", "content_warning"} {
+		if _, ok := spec.Components.Schemas["PostCreateRequest"].Properties[field]; !ok {
+			t.Errorf("PostCreateRequest schema is missing %q", field)
+		}
+	}
+	for _, field := range []string{"access_token", "refresh_token"} {
+		if _, ok := spec.Components.Schemas["UserLoginResponse"].Properties[field]; !ok {
+			t.Errorf("UserLoginResponse schema is missing %q", field)
+		}
+	}
+}
+
+func TestDocsUI(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "openapi.json") {
+		t.Fatalf("GET /docs = %d", rec.Code)
+	}
+}
+
+// 签名有效但已过期的令牌返回 token_expired
+func TestExpiredToken(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		ID: 1, UserName: "alice",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+	}).SignedString([]byte(auth.GetJwtAccessSecretKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for token, code := range map[string]string{token: "token_expired", token + "x": "unauthorized"} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/user/isauth", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		e.ServeHTTP(rec, req)
+		if rec.Code != http.StatusUnauthorized || !strings.Contains(rec.Body.String(), `"code":"`+code+`"`) {
+			t.Errorf("GET /v1/user/isauth = %d %s, want %s", rec.Code, rec.Body.String(), code)
+		}
+	}
+}
+
+func TestLegacyRoutesDeprecated(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/user/isauth", nil))
+	if rec.Header().Get("Deprecation") == "" || rec.Header().Get("Sunset") == "" {
+		t.Errorf("legacy route should have Deprecation and Sunset headers, got %v", rec.Header())
+	}
+	if link := rec.Header().Get("Link"); link != `</v1/user/isauth>; rel="successor-version"` {
+		t.Errorf("Link = %q", link)
+	}
+
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/user/isauth", nil))
+	if rec.Header().Get("Deprecation") != "" {
+		t.Errorf("versioned route should not be deprecated")
+	}
+	if rec.Code != http.StatusUnauthorized && rec.Code != http.StatusBadRequest {
+		t.Errorf("GET /v1/user/isauth without token = %d", rec.Code)
+	}
+
+	// 分组中间件先于路由执行，鉴权失败时仍应带有弃用提示
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/filter", nil))
+	if rec.Header().Get("Deprecation") == "" {
+		t.Errorf("legacy admin route should be deprecated even when unauthorized")
+	}
+}
+
+func TestErrorResponses(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	var body struct {
+		Code int `json:"code"`
+		Data struct {
+			Code      string `json:"code"`
+			RequestID string `json:"request_id"`
+		} `json:"data"`
+	}
+	cases := []struct {
+		method    string
+		path      string
+		requestID string
+		status    int
+		code      string
+	}{
+		{http.MethodGet, "/v1/nothing", "", http.StatusNotFound, "not_found"},
+		{http.MethodGet, "/v1/user/isauth", "client-id.1", http.StatusUnauthorized, "unauthorized"},
+		{http.MethodPost, "/v1/user/login", "bad id\n", http.StatusBadRequest, "invalid_request"},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader("{"))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.requestID != "" {
+			req.Header.Set(echo.HeaderXRequestID, tc.requestID)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v", tc.method, tc.path, err)
+		}
+		if rec.Code != tc.status || body.Code != tc.status || body.Data.Code != tc.code {
+			t.Errorf("%s %s = %d %q, want %d %q", tc.method, tc.path, rec.Code, body.Data.Code, tc.status, tc.code)
+		}
+		id := rec.Header().Get(echo.HeaderXRequestID)
+		if id == "" || body.Data.RequestID != id {
+			t.Errorf("%s %s: request_id %q does not match header %q", tc.method, tc.path, body.Data.RequestID, id)
+		}
+		if tc.requestID == "client-id.1" && id != tc.requestID {
+			t.Errorf("valid client request ID was replaced by %q", id)
+		}
+		if tc.requestID == "bad id\n" && id == tc.requestID {
+			t.Errorf("invalid client request ID was kept")
+		}
+		if strings.Contains(rec.Body.String(), "unexpected EOF") {
+			t.Errorf("%s %s leaks the parse error: %s", tc.method, tc.path, rec.Body.String())
+		}
+	}
+}
+
+func TestBindValidation(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/user/register", strings.NewReader(`{"email":"not-an-email","bio":"`+strings.Repeat("b", 161)+`"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var body struct {
+		Data struct {
+			Code    string `json:"code"`
+			Details []struct {
+				Field string `json:"field"`
+				Rule  string `json:"rule"`
+				Limit int    `json:"limit"`
+			} `json:"details"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusBadRequest || body.Data.Code != "invalid_parameter" {
+		t.Fatalf("POST /v1/user/register = %d %q", rec.Code, body.Data.Code)
+	}
+	// 所有不符合规则的字段一并返回
+	got := make(map[string]string)
+	for _, d := range body.Data.Details {
+		got[d.Field] = d.Rule
+	}
+	want := map[string]string{"user_name": "required", "email": "email", "password": "required", "bio": "max"}
+	for field, rule := range want {
+		if got[field] != rule {
+			t.Errorf("details[%s] = %q, want %q (all: %+v)", field, got[field], rule, body.Data.Details)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("details = %+v", body.Data.Details)
+	}
+}
+
+func TestBindQueryAndPath(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	cases := []struct {
+		path  string
+		field string
+		rule  string
+	}{
+		{"/v1/post?limit=500", "limit", "lte"},
+		{"/v1/post?limit=ten", "limit", "type"},
+		{"/v1/post?order_by=oldest", "order_by", "oneof"},
+		{"/v1/user/abc", "user_id", "type"},
+		{"/v1/list/timeline?list_id=1&limit=500", "limit", "lte"},
+		{"/v1/list/timeline?list_id=1&limit=-1", "limit", "gte"},
+		{"/v1/list/timeline?limit=10", "list_id", "required"},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.path, nil))
+		var body struct {
+			Data struct {
+				Code    string `json:"code"`
+				Details []struct {
+					Field string `json:"field"`
+					Rule  string `json:"rule"`
+				} `json:"details"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("GET %s: %v", tc.path, err)
+		}
+		if rec.Code != http.StatusBadRequest || body.Data.Code != "invalid_parameter" || len(body.Data.Details) != 1 ||
+			body.Data.Details[0].Field != tc.field || body.Data.Details[0].Rule != tc.rule {
+			t.Errorf("GET %s = %d %s", tc.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// 类型错误的提示按 Accept-Language 翻译
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/v1/user/abc", nil)
+	req.Header.Set("Accept-Language", "zh")
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"msg":"user_id 的类型无效。"`) {
+		t.Errorf("GET /v1/user/abc in zh = %d %s", rec.Code, rec.Body.String())
+	}
+
+	// POST 请求不读取查询参数，user_name 仍为空
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/v1/user/register?user_name=alice", strings.NewReader(`{"email":"a@example.com","password":"x"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"field":"user_name"`) {
+		t.Errorf("POST /v1/user/register with user_name in query = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLocalizedMessages(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	cases := []struct {
+		acceptLanguage string
+		lang           string
+		msg            string
+		detail         string
+	}{
+		{"", "en", "Invalid parameter.", "user_name is required."},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh", "参数无效。", "user_name 为必填项。"},
+		{"fr-FR", "en", "Invalid parameter.", "user_name is required."},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/v1/user/register", strings.NewReader(`{}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.acceptLanguage != "" {
+			req.Header.Set("Accept-Language", tc.acceptLanguage)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var body struct {
+			Data struct {
+				Code    string `json:"code"`
+				Msg     string `json:"msg"`
+				Details []struct {
+					Field string `json:"field"`
+					Msg   string `json:"msg"`
+				} `json:"details"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if got := rec.Header().Get("Content-Language"); got != tc.lang {
+			t.Errorf("Accept-Language %q: Content-Language = %q, want %q", tc.acceptLanguage, got, tc.lang)
+		}
+		if !strings.Contains(rec.Header().Get(echo.HeaderVary), "Accept-Language") {
+			t.Errorf("Accept-Language %q: Vary = %q", tc.acceptLanguage, rec.Header().Get(echo.HeaderVary))
+		}
+		if body.Data.Code != "invalid_parameter" || body.Data.Msg != tc.msg {
+			t.Errorf("Accept-Language %q: data = %q %q, want %q", tc.acceptLanguage, body.Data.Code, body.Data.Msg, tc.msg)
+		}
+		found := false
+		for _, d := range body.Data.Details {
+			if d.Field == "user_name" {
+				found = true
+				if d.Msg != tc.detail {
+					t.Errorf("Accept-Language %q: user_name msg = %q, want %q", tc.acceptLanguage, d.Msg, tc.detail)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("Accept-Language %q: missing user_name detail: %s", tc.acceptLanguage, rec.Body.String())
+		}
+	}
+}
+
+func TestGraphQL(t *testing.T) {
+	e := echo.New()
+	router.Load(e)
+
+	deep := "{ viewer" + strings.Repeat(" { posts(limit: 1) { author", 5) + " { id }" + strings.Repeat(" } }", 5) + " }"
+	deepIntrospection := "{ __schema { types { fields { type" + strings.Repeat(" { ofType", 15) + " { name }" + strings.Repeat(" }", 15) + " } } } }"
+	cases := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"depth", deep, "query_too_deep"},
+		{"introspection depth", deepIntrospection, "query_too_deep"},
+		{"complexity", "{ posts(limit: 100) { author { posts(limit: 100) { id } } } }", "query_too_complex"},
+		{"mutation without token", `mutation { createPost(content: "hi") { status } }`, "unauthorized"},
+		// 经由 REST 接口执行，错误码来自 TokenVerificationMiddleware
+		{"mutation without token", "mutation { likePost(postId: 1) { status } }", "unauthorized"},
+	}
+	for _, tc := range cases {
+		body, _ := json.Marshal(map[string]string{"query": tc.query})
+		req := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		var result struct {
+			Errors []struct {
+				Message    string `json:"message"`
+				Extensions struct {
+					Code      string `json:"code"`
+					RequestID string `json:"request_id"`
+				} `json:"extensions"`
+			} `json:"errors"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if rec.Code != http.StatusOK || len(result.Errors) == 0 {
+			t.Errorf("%s: status %d, body %s", tc.name, rec.Code, rec.Body.String())
+			continue
+		}
+		if got := result.Errors[0].Extensions; got.Code != tc.code || got.RequestID != rec.Header().Get(echo.HeaderXRequestID) {
+			t.Errorf("%s: extensions = %+v, want code %q and request id %q", tc.name, got, tc.code, rec.Header().Get(echo.HeaderXRequestID))
+		}
+	}
+
+	// 常见深度的内省查询不受普通查询的深度限制
+	introspection := "{ __schema { types { name fields { name type" + strings.Repeat(" { name ofType", 7) + " { name }" + strings.Repeat(" }", 7) + " } } } }"
+	body, _ := json.Marshal(map[string]string{"query": introspection})
+	req := httptest.NewRequest(http.MethodPost, "/v1/graphql", bytes.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"errors"`) {
+		t.Errorf("introspection: status %d, body %.200s", rec.Code, rec.Body.String())
+	}
+}
+
+// GET /post 按 user_id 或 user_name 筛选发布者
+func TestPostAuthorFilter(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := model.ConnectWith(conn); err != nil {
+		t.Fatal(err)
+	}
+	e := echo.New()
+	router.Load(e)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "users" WHERE user_name = \$1`).WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_name"}).AddRow(7, "alice"))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "posts" WHERE user_id = \$1`).WithArgs(7, false).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+	mock.ExpectCommit()
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/post?user_name=alice", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("GET /v1/post?user_name=alice = %d %s", rec.Code, rec.Body.String())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
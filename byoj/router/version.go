@@ -0,0 +1,124 @@
+package router
+
+import (
+	"byoj/docs"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+/**
+ * API 版本
+ * 新版本由旧版本 derive 得到，默认沿用旧版本的全部处理函数，仅替换返回格式有变化的接口
+ **/
+type apiVersion struct {
+	prefix string
+	// 该版本所有路由附加的中间件，先于路由自身的中间件执行
+	middleware []echo.MiddlewareFunc
+	// 被替换的处理函数，键为原处理函数名
+	handlers map[string]echo.HandlerFunc
+	// 该版本注册的路由，键为 "METHOD path"
+	routes map[string]bool
+}
+
+func newAPIVersion(prefix string) *apiVersion {
+	return &apiVersion{prefix: prefix, handlers: make(map[string]echo.HandlerFunc), routes: make(map[string]bool)}
+}
+
+// 以当前版本为基础创建新版本，已替换的处理函数一并继承
+func (v *apiVersion) derive(prefix string, middleware ...echo.MiddlewareFunc) *apiVersion {
+	d := newAPIVersion(prefix)
+	d.middleware = append(append(d.middleware, v.middleware...), middleware...)
+	for name, h := range v.handlers {
+		d.handlers[name] = h
+	}
+	return d
+}
+
+// 在该版本中用 h 替换 base 处理的接口
+func (v *apiVersion) replace(base echo.HandlerFunc, h echo.HandlerFunc) {
+	v.handlers[docs.HandlerName(base)] = h
+}
+
+// 去掉属于该版本的路由
+func (v *apiVersion) exclude(routes []*echo.Route) []*echo.Route {
+	list := make([]*echo.Route, 0, len(routes))
+	for _, route := range routes {
+		if !v.routes[route.Method+" "+route.Path] {
+			list = append(list, route)
+		}
+	}
+	return list
+}
+
+func (v *apiVersion) handler(h echo.HandlerFunc) echo.HandlerFunc {
+	if replaced, ok := v.handlers[docs.HandlerName(h)]; ok {
+		return replaced
+	}
+	return h
+}
+
+/**
+ * 按版本注册路由，用法与 echo.Group 相同
+ * 分组带有中间件时，版本中间件随分组中间件一起注册，保证先于鉴权等中间件执行
+ **/
+type apiGroup struct {
+	group   *echo.Group
+	version *apiVersion
+	// 版本中间件已注册在分组上
+	wrapped bool
+}
+
+func (v *apiVersion) mount(e *echo.Echo) apiGroup {
+	return apiGroup{group: e.Group(v.prefix), version: v}
+}
+
+func (r apiGroup) Group(prefix string, middleware ...echo.MiddlewareFunc) apiGroup {
+	if len(middleware) == 0 || r.wrapped {
+		return apiGroup{group: r.group.Group(prefix, middleware...), version: r.version, wrapped: r.wrapped}
+	}
+	m := append(append([]echo.MiddlewareFunc{}, r.version.middleware...), middleware...)
+	return apiGroup{group: r.group.Group(prefix, m...), version: r.version, wrapped: true}
+}
+
+func (r apiGroup) add(method string, path string, h echo.HandlerFunc, middleware []echo.MiddlewareFunc) *echo.Route {
+	if !r.wrapped {
+		middleware = append(append([]echo.MiddlewareFunc{}, r.version.middleware...), middleware...)
+	}
+	route := r.group.Add(method, path, r.version.handler(h), middleware...)
+	r.version.routes[route.Method+" "+route.Path] = true
+	return route
+}
+
+func (r apiGroup) GET(path string, h echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route {
+	return r.add(http.MethodGet, path, h, middleware)
+}
+
+func (r apiGroup) POST(path string, h echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route {
+	return r.add(http.MethodPost, path, h, middleware)
+}
+
+/**
+ * 为弃用的接口添加 Deprecation、Sunset 及指向新版本的 Link 响应头
+ * @param: deprecatedAt 弃用时间
+ * @param: sunset 计划下线时间
+ * @param: successor 新版本路径前缀，如 "/v1"
+ **/
+func deprecated(deprecatedAt time.Time, sunset time.Time, successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			h := c.Response().Header()
+			h.Set("Deprecation", "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+			h.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			path := c.Request().URL.Path
+			if !strings.HasPrefix(path, "/") {
+				path = "/" + path
+			}
+			h.Add("Link", "<"+successor+path+`>; rel="successor-version"`)
+			return next(c)
+		}
+	}
+}
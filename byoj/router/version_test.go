@@ -0,0 +1,54 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo"
+)
+
+func oldHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "old")
+}
+
+func newHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "new")
+}
+
+func otherHandler(c echo.Context) error {
+	return c.String(http.StatusOK, "other")
+}
+
+func testRoutes(api apiGroup) {
+	api.GET("/a", oldHandler)
+	api.GET("/b", otherHandler)
+}
+
+func TestVersionReplace(t *testing.T) {
+	v1 := newAPIVersion("/v1")
+	v2 := v1.derive("/v2")
+	v2.replace(oldHandler, newHandler)
+	// 继承 v2 中已替换的处理函数
+	v3 := v2.derive("/v3")
+
+	e := echo.New()
+	for _, v := range []*apiVersion{v1, v2, v3} {
+		testRoutes(v.mount(e))
+	}
+
+	cases := map[string]string{
+		"/v1/a": "old",
+		"/v1/b": "other",
+		"/v2/a": "new",
+		"/v2/b": "other",
+		"/v3/a": "new",
+	}
+	for path, want := range cases {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != want {
+			t.Errorf("GET %s = %d %q, want %q", path, rec.Code, rec.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"byoj/controllers"
+	"byoj/model"
+	"byoj/rpc/pb"
+	"context"
+)
+
+type authService struct {
+	pb.UnimplementedAuthServiceServer
+}
+
+func (s *authService) Register(ctx context.Context, req *pb.RegisterRequest) (*pb.StatusReply, error) {
+	status, err := controllers.RegisterUser(langOf(ctx), controllers.UserRegisterRequest{
+		UserName:    req.UserName,
+		Email:       req.Email,
+		PasswordMD5: req.Password,
+		RealName:    req.RealName,
+		Bio:         req.Bio,
+	})
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return &pb.StatusReply{Status: status.Status}, nil
+}
+
+func (s *authService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginReply, error) {
+	login, err := controllers.LoginUser(model.User{
+		ID:          req.UserId,
+		UserName:    req.UserName,
+		Email:       req.Email,
+		PasswordMD5: req.Password,
+	})
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return &pb.LoginReply{
+		UserId:                     login.ID,
+		UserName:                   login.UserName,
+		AccessToken:                login.AccessToken,
+		AccessTokenExpirationTime:  login.AccessTokenExpireAt,
+		RefreshToken:               login.RefreshToken,
+		RefreshTokenExpirationTime: login.RefreshTokenExpireAt,
+	}, nil
+}
+
+// 令牌及用户状态已由拦截器校验
+func (s *authService) IsAuth(ctx context.Context, req *pb.IsAuthRequest) (*pb.StatusReply, error) {
+	return &pb.StatusReply{Status: "OK"}, nil
+}
@@ -0,0 +1,100 @@
+package rpc
+
+import (
+	"byoj/controllers"
+	"byoj/rpc/pb"
+	"byoj/utils/i18n"
+	"byoj/utils/logs"
+	"context"
+	"net/http"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HTTP 状态码对应的 gRPC 状态码，未列出的 4xx 为 InvalidArgument，5xx 为 Internal
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusMethodNotAllowed:      codes.Unimplemented,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusGone:                  codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusServiceUnavailable:    codes.Unavailable,
+}
+
+func codeOf(httpStatus int) codes.Code {
+	if code, ok := statusCodes[httpStatus]; ok {
+		return code
+	}
+	if httpStatus >= http.StatusInternalServerError {
+		return codes.Internal
+	}
+	return codes.InvalidArgument
+}
+
+/**
+ * 构造 gRPC 状态，错误码及请求 ID 放在 details 中的 byitter.v1.Error
+ * @param: message 展示文本，已按请求的语言翻译
+ **/
+func newStatus(httpStatus int, message string, detail *pb.Error) error {
+	st := status.New(codeOf(httpStatus), message)
+	if withDetails, err := st.WithDetails(detail); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+// 返回目录中的错误，与 ResponseError 一样只记录原始错误，不返回给客户端
+func newError(ctx context.Context, apiErr *controllers.APIError, err error) error {
+	return requestError(ctx, &controllers.RequestError{API: apiErr, Cause: err})
+}
+
+func internalError(ctx context.Context, err error) error {
+	return newError(ctx, controllers.ErrInternal, err)
+}
+
+// 共用的处理函数返回的错误，文本及字段级错误按请求的语言翻译
+func requestError(ctx context.Context, err error) error {
+	reqErr := controllers.AsRequestError(err)
+	fields := []zap.Field{zap.String("code", reqErr.API.Code), zap.String("requestID", requestIDOf(ctx))}
+	if reqErr.Cause != nil {
+		fields = append(fields, zap.Error(reqErr.Cause))
+	}
+	logMessage := "gRPC call failed."
+	if reqErr.LogMessage != "" {
+		logMessage = reqErr.LogMessage
+	}
+	if reqErr.API.Status >= http.StatusInternalServerError {
+		logs.Error(logMessage, fields...)
+	} else {
+		logs.Debug(logMessage, fields...)
+	}
+
+	lang := langOf(ctx)
+	localized := reqErr.LocalizedDetails(lang)
+	details := make([]*pb.FieldError, 0, len(localized))
+	for _, detail := range localized {
+		details = append(details, &pb.FieldError{
+			Field:  detail.Field,
+			Rule:   detail.Rule,
+			Msg:    detail.Message,
+			Limit:  int64(detail.Limit),
+			Actual: int64(detail.Actual),
+		})
+	}
+	return newStatus(reqErr.API.Status, reqErr.API.Message(lang), &pb.Error{
+		Code:      reqErr.API.Code,
+		RequestId: requestIDOf(ctx),
+		Details:   details,
+	})
+}
+
+// 请求的语言，按 metadata 中的 accept-language 协商
+func langOf(ctx context.Context) string {
+	return i18n.Negotiate(metadataValue(ctx, "accept-language"))
+}
@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"byoj/controllers"
+	"byoj/controllers/auth"
+	"byoj/controllers/middleware"
+	"byoj/rpc/pb"
+	"byoj/utils/logs"
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// 无需鉴权的方法，携带有效令牌时同样解析出当前用户；其余方法必须携带有效的 access token
+var publicMethods = map[string]bool{
+	pb.AuthService_Register_FullMethodName:    true,
+	pb.AuthService_Login_FullMethodName:       true,
+	pb.UserService_GetUser_FullMethodName:     true,
+	pb.PostService_ListPosts_FullMethodName:   true,
+	pb.PostService_StreamPosts_FullMethodName: true,
+}
+
+type requestIDKey struct{}
+
+type claimsKey struct{}
+
+func unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := prepare(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func streamInterceptor(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := prepare(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+// 替换 Context 的 ServerStream，使处理函数取得请求 ID 及当前用户
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+/**
+ * 分配请求 ID 并通过响应 metadata 返回，再校验 authorization 中的 Bearer 令牌
+ * 需要鉴权的方法与 TokenVerificationMiddleware 一样检查用户状态；无需鉴权的方法只解析令牌，与 REST 的 GetViewerID 一致
+ **/
+func prepare(ctx context.Context, method string) (context.Context, error) {
+	logs.Debug("gRPC " + method)
+
+	requestID := middleware.ResolveRequestID(metadataValue(ctx, "x-request-id"))
+	ctx = context.WithValue(ctx, requestIDKey{}, requestID)
+	// 响应 metadata 发送失败只影响客户端对照日志，不中断请求
+	_ = grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestID))
+
+	claims, err := controllers.VerifyToken(metadataValue(ctx, "authorization"))
+	if err == nil && !publicMethods[method] {
+		_, err = controllers.Authenticate(claims)
+	}
+	if err == nil {
+		return context.WithValue(ctx, claimsKey{}, claims), nil
+	}
+	if publicMethods[method] {
+		return ctx, nil
+	}
+	return ctx, requestError(ctx, err)
+}
+
+func metadataValue(ctx context.Context, key string) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// 当前请求的关联 ID，与 REST 接口的 X-Request-ID 一致
+func requestIDOf(ctx context.Context) string {
+	if id, ok := ctx.Value(requestIDKey{}).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// 令牌中的声明，未登录时为空
+func claimsOf(ctx context.Context) auth.Claims {
+	claims, _ := ctx.Value(claimsKey{}).(auth.Claims)
+	return claims
+}
+
+// 令牌对应的用户 ID，未登录时返回 0
+func viewerIDOf(ctx context.Context) uint32 {
+	return claimsOf(ctx).ID
+}
@@ -0,0 +1,4 @@
+// gRPC 接口的消息及服务定义，由 proto/byitter/v1 下的 .proto 文件生成，不要手动修改
+package pb
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=byoj --go-grpc_out=../.. --go-grpc_opt=module=byoj byitter/v1/common.proto byitter/v1/auth.proto byitter/v1/user.proto byitter/v1/post.proto
@@ -0,0 +1,205 @@
+package rpc
+
+import (
+	"byoj/controllers"
+	"byoj/model"
+	"byoj/rpc/pb"
+	"byoj/utils/logs"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// 推送时查询新帖子的间隔
+	streamPollInterval = 2 * time.Second
+	// 每次查询的最大帖子数，达到时不等待间隔继续查询
+	streamBatchSize = 50
+	// 可见时间在事务提交前写入，每次查询回看这段时间，以免漏掉较晚提交的帖子
+	streamOverlap = time.Minute
+)
+
+type postService struct {
+	pb.UnimplementedPostServiceServer
+}
+
+func (s *postService) CreatePost(ctx context.Context, req *pb.CreatePostRequest) (*pb.CreatePostReply, error) {
+	created, err := controllers.CreatePost(langOf(ctx), claimsOf(ctx), controllers.PostCreateRequest{
+		AuthorID:       req.UserId,
+		AuthorName:     req.UserName,
+		AuthorEmail:    req.Email,
+		Content:        req.Content,
+		MediaIDs:       req.MediaIds,
+		ContentWarning: req.ContentWarning,
+		SensitiveMedia: req.SensitiveMedia,
+	})
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return &pb.CreatePostReply{
+		Status:   created.Status,
+		PostId:   created.PostID,
+		IsPublic: created.IsPublic,
+		Held:     created.Held,
+	}, nil
+}
+
+func (s *postService) ListPosts(ctx context.Context, req *pb.ListPostsRequest) (*pb.ListPostsReply, error) {
+	list, err := controllers.GetPosts(viewerIDOf(ctx), controllers.PostGetRequest{
+		AuthorID:    req.UserId,
+		AuthorName:  req.UserName,
+		AuthorEmail: req.Email,
+		Limit:       int(req.Limit),
+		OrderBy:     req.OrderBy,
+		StartTime:   req.StartTime,
+	})
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	posts := make([]*pb.Post, 0, len(list.PostList))
+	for _, post := range list.PostList {
+		posts = append(posts, newPost(post))
+	}
+	return &pb.ListPostsReply{Posts: posts}, nil
+}
+
+func (s *postService) LikePost(ctx context.Context, req *pb.LikeRequest) (*pb.StatusReply, error) {
+	status, err := controllers.LikePost(langOf(ctx), viewerIDOf(ctx), controllers.LikeRequest{PostID: req.PostId})
+	return statusReply(ctx, status, err)
+}
+
+func (s *postService) UnlikePost(ctx context.Context, req *pb.LikeRequest) (*pb.StatusReply, error) {
+	status, err := controllers.UnlikePost(langOf(ctx), viewerIDOf(ctx), controllers.LikeRequest{PostID: req.PostId})
+	return statusReply(ctx, status, err)
+}
+
+/**
+ * 按可见时间推送新发布及审核通过的公开帖子，直到客户端断开
+ * 定时查询数据库而不是在发布时通知，多个实例部署时同样能推送其他实例发布的帖子
+ * 帖子 ID 与可见时间都不保证按提交顺序递增，每次查询回看 streamOverlap，用已推送的 ID 去重
+ **/
+func (s *postService) StreamPosts(req *pb.StreamPostsRequest, stream pb.PostService_StreamPostsServer) error {
+	ctx := stream.Context()
+	viewerID := viewerIDOf(ctx)
+
+	watermark := time.Now()
+	if req.AfterId != 0 {
+		post, err := model.FindPostByPostID(req.AfterId)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return internalError(ctx, err)
+		}
+		if err == nil {
+			watermark = post.PublishedAt
+			// 早于 published_at 字段的帖子没有可见时间
+			if watermark.IsZero() {
+				watermark = post.Time
+			}
+		}
+	}
+	// 已推送的帖子 ID 及其可见时间
+	seen := map[uint32]time.Time{req.AfterId: watermark}
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+	for {
+		since := watermark.Add(-streamOverlap)
+		after, afterID := since, uint32(0)
+		for {
+			posts, err := model.GetPublicPostsPublishedAfter(after, afterID, streamBatchSize)
+			if err != nil {
+				return internalError(ctx, err)
+			}
+			unseen := make([]model.Post, 0, len(posts))
+			for _, post := range posts {
+				if _, ok := seen[post.ID]; !ok {
+					seen[post.ID] = post.PublishedAt
+					unseen = append(unseen, post)
+				}
+				if post.PublishedAt.After(watermark) {
+					watermark = post.PublishedAt
+				}
+			}
+			for _, post := range controllers.NewPostResponses(hideBlockedPosts(unseen, viewerID), viewerID) {
+				if err := stream.Send(newPost(post)); err != nil {
+					return err
+				}
+			}
+			if len(posts) < streamBatchSize {
+				break
+			}
+			after, afterID = posts[len(posts)-1].PublishedAt, posts[len(posts)-1].ID
+		}
+		// 回看范围之外的帖子不会再查询到
+		for postID, publishedAt := range seen {
+			if publishedAt.Before(since) {
+				delete(seen, postID)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// 过滤与当前用户存在屏蔽关系的作者的帖子，未登录时不过滤
+func hideBlockedPosts(posts []model.Post, viewerID uint32) []model.Post {
+	if viewerID == 0 || len(posts) == 0 {
+		return posts
+	}
+	blocked := make(map[uint32]bool)
+	visible := make([]model.Post, 0, len(posts))
+	for _, post := range posts {
+		isBlocked, ok := blocked[post.AuthorID]
+		if !ok {
+			var err error
+			isBlocked, err = model.IsBlockedBetween(viewerID, post.AuthorID)
+			if err != nil {
+				logs.Warn("Check block between users failed.", zap.Uint32("viewerID", viewerID), zap.Error(err))
+			}
+			blocked[post.AuthorID] = isBlocked
+		}
+		if !isBlocked {
+			visible = append(visible, post)
+		}
+	}
+	return visible
+}
+
+func newPost(post controllers.PostResponse) *pb.Post {
+	mediaList := make([]*pb.Media, 0, len(post.MediaList))
+	for _, media := range post.MediaList {
+		mediaList = append(mediaList, &pb.Media{
+			MediaId:     media.MediaID,
+			Url:         media.URL,
+			ContentType: media.ContentType,
+			Size:        media.Size,
+			Width:       int32(media.Width),
+			Height:      int32(media.Height),
+			AltText:     media.AltText,
+			Blurhash:    media.Blurhash,
+		})
+	}
+	return &pb.Post{
+		PostId:         post.PostID,
+		UserId:         post.AuthorID,
+		UserName:       post.AuthorName,
+		Time:           post.Time,
+		Content:        post.Content,
+		Text:           post.Text,
+		Html:           post.HTML,
+		IsPublic:       post.IsPublic,
+		Sensitive:      post.Sensitive,
+		Collapsed:      post.Collapsed,
+		Bookmarked:     post.Bookmarked,
+		Liked:          post.Liked,
+		LikeCount:      post.LikeCount,
+		MediaList:      mediaList,
+		ContentWarning: post.ContentWarning,
+		SensitiveMedia: post.SensitiveMedia,
+	}
+}
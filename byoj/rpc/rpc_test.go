@@ -0,0 +1,131 @@
+package rpc_test
+
+import (
+	"byoj/controllers/auth"
+	"byoj/rpc"
+	"byoj/rpc/pb"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func dial(t *testing.T) *grpc.ClientConn {
+	s := rpc.NewServer()
+	listener := bufconn.Listen(1 << 20)
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// 返回状态中 byitter.v1.Error 详情
+func errorDetail(t *testing.T, err error) (*status.Status, *pb.Error) {
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("err = %v, want a gRPC status", err)
+	}
+	for _, detail := range st.Details() {
+		if e, ok := detail.(*pb.Error); ok {
+			return st, e
+		}
+	}
+	t.Fatalf("status %v has no byitter.v1.Error detail", st)
+	return nil, nil
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	conn := dial(t)
+	posts := pb.NewPostServiceClient(conn)
+
+	for _, token := range []string{"", "Bearer not-a-jwt", "Basic abc"} {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", token)
+		}
+		var header metadata.MD
+		_, err := posts.CreatePost(ctx, &pb.CreatePostRequest{Content: "hello"}, grpc.Header(&header))
+		st, detail := errorDetail(t, err)
+		if st.Code() != codes.Unauthenticated || detail.Code != "unauthorized" {
+			t.Errorf("token %q: %v %q, want Unauthenticated unauthorized", token, st.Code(), detail.Code)
+		}
+		if ids := header.Get("x-request-id"); len(ids) != 1 || ids[0] != detail.RequestId {
+			t.Errorf("token %q: x-request-id = %v, detail request_id = %q", token, ids, detail.RequestId)
+		}
+	}
+}
+
+func TestExpiredToken(t *testing.T) {
+	conn := dial(t)
+	posts := pb.NewPostServiceClient(conn)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		ID: 1, UserName: "alice",
+		StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+	}).SignedString([]byte(auth.GetJwtAccessSecretKey()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	_, err = posts.CreatePost(ctx, &pb.CreatePostRequest{Content: "hello"})
+	st, detail := errorDetail(t, err)
+	if st.Code() != codes.Unauthenticated || detail.Code != "token_expired" {
+		t.Errorf("expired token: %v %q, want Unauthenticated token_expired", st.Code(), detail.Code)
+	}
+}
+
+func TestErrorDetails(t *testing.T) {
+	conn := dial(t)
+	auth := pb.NewAuthServiceClient(conn)
+
+	cases := []struct {
+		acceptLanguage string
+		msg            string
+		detail         string
+	}{
+		{"", "Invalid parameter.", "user_name is required."},
+		{"zh-CN", "参数无效。", "user_name 为必填项。"},
+	}
+	for _, tc := range cases {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "rpc-test-1")
+		if tc.acceptLanguage != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "accept-language", tc.acceptLanguage)
+		}
+		_, err := auth.Register(ctx, &pb.RegisterRequest{})
+		st, detail := errorDetail(t, err)
+		if st.Code() != codes.InvalidArgument || detail.Code != "invalid_parameter" || st.Message() != tc.msg {
+			t.Errorf("Accept-Language %q: %v %q %q", tc.acceptLanguage, st.Code(), detail.Code, st.Message())
+		}
+		// 沿用客户端传入的请求 ID
+		if detail.RequestId != "rpc-test-1" {
+			t.Errorf("Accept-Language %q: request_id = %q", tc.acceptLanguage, detail.RequestId)
+		}
+		found := false
+		for _, d := range detail.Details {
+			if d.Field == "user_name" {
+				found = d.Msg == tc.detail
+			}
+		}
+		if !found {
+			t.Errorf("Accept-Language %q: details = %v, want user_name %q", tc.acceptLanguage, detail.Details, tc.detail)
+		}
+	}
+}
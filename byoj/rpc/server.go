@@ -0,0 +1,22 @@
+package rpc
+
+import (
+	"byoj/rpc/pb"
+
+	"google.golang.org/grpc"
+)
+
+/**
+ * 创建 gRPC 服务
+ * 方法调用与 REST 接口相同的处理函数，鉴权、校验及业务规则与 REST 完全一致
+ **/
+func NewServer() *grpc.Server {
+	s := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor),
+		grpc.StreamInterceptor(streamInterceptor),
+	)
+	pb.RegisterAuthServiceServer(s, &authService{})
+	pb.RegisterUserServiceServer(s, &userService{})
+	pb.RegisterPostServiceServer(s, &postService{})
+	return s
+}
@@ -0,0 +1,58 @@
+package rpc
+
+import (
+	"byoj/controllers"
+	"byoj/model"
+	"byoj/rpc/pb"
+	"context"
+)
+
+type userService struct {
+	pb.UnimplementedUserServiceServer
+}
+
+func (s *userService) GetUser(ctx context.Context, req *pb.UserRequest) (*pb.User, error) {
+	user, err := controllers.GetUser(controllers.UserGetRequest{
+		ID:       req.UserId,
+		UserName: req.UserName,
+		Email:    req.Email,
+	})
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return &pb.User{
+		UserId:     user.ID,
+		UserName:   user.UserName,
+		Email:      user.Email,
+		RealName:   user.RealName,
+		Bio:        user.Bio,
+		Verified:   user.Verified,
+		Deleted:    user.Deleted,
+		Suspended:  user.Suspended,
+		AvatarUrls: user.AvatarURLs,
+		BannerUrls: user.BannerURLs,
+	}, nil
+}
+
+func (s *userService) Follow(ctx context.Context, req *pb.UserRequest) (*pb.StatusReply, error) {
+	status, err := controllers.FollowUser(langOf(ctx), viewerIDOf(ctx), newUserRequest(req))
+	return statusReply(ctx, status, err)
+}
+
+func (s *userService) Unfollow(ctx context.Context, req *pb.UserRequest) (*pb.StatusReply, error) {
+	status, err := controllers.UnfollowUser(langOf(ctx), viewerIDOf(ctx), newUserRequest(req))
+	return statusReply(ctx, status, err)
+}
+
+// 关注等操作只取指定目标用户的字段
+func newUserRequest(req *pb.UserRequest) model.User {
+	return model.User{ID: req.UserId, UserName: req.UserName, Email: req.Email}
+}
+
+// 只返回状态文本的方法
+func statusReply(ctx context.Context, status controllers.StatusMessage, err error) (*pb.StatusReply, error) {
+	if err != nil {
+		return nil, requestError(ctx, err)
+	}
+	return &pb.StatusReply{Status: status.Status}, nil
+}
@@ -2,7 +2,9 @@ package server
 
 import (
 	"byoj/router"
+	"byoj/rpc"
 	"byoj/utils/logs"
+	"net"
 	"strconv"
 
 	"github.com/labstack/echo"
@@ -12,6 +14,8 @@ import (
 type Server struct {
 	Hostname string `yaml:"hostname"`
 	Port     int    `yaml:"port"`
+	// gRPC 接口监听的端口，与 REST 接口共用 hostname
+	GRPCPort int `yaml:"grpc-port"`
 }
 
 func Run(s Server) error {
@@ -27,9 +31,28 @@ func Run(s Server) error {
 	if s.Port == 0 {
 		s.Port = 3435
 	}
+	if s.GRPCPort == 0 {
+		s.GRPCPort = 3436
+	}
+
+	grpcAddress := s.Hostname + ":" + strconv.Itoa(s.GRPCPort)
+	listener, err := net.Listen("tcp", grpcAddress)
+	if err != nil {
+		logs.Error("gRPC server listen failed at "+grpcAddress+". ", zap.Error(err))
+		return err
+	}
+	grpcServer := rpc.NewServer()
+	defer grpcServer.Stop()
+	go func() {
+		err := grpcServer.Serve(listener)
+		if err != nil {
+			logs.Error("gRPC server run failed at "+grpcAddress+". ", zap.Error(err))
+		}
+	}()
+	logs.Info("gRPC server started at " + grpcAddress)
 
 	address := s.Hostname + ":" + strconv.Itoa(s.Port)
-	err := e.Start(address)
+	err = e.Start(address)
 	if err != nil {
 		logs.Error("Server run failed at "+address+". ", zap.Error(err))
 		return err
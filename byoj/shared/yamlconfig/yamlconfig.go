@@ -1,9 +1,14 @@
 package yamlconfig
 
 import (
+	"byoj/controllers"
 	"byoj/controllers/auth"
+	"byoj/media"
 	"byoj/model"
+	"byoj/preview"
 	"byoj/shared/server"
+	"byoj/storage"
+	"byoj/utils/content"
 	"byoj/utils/logs"
 
 	"github.com/gookit/config/v2"
@@ -12,9 +17,14 @@ import (
 )
 
 type Configuration struct {
-	Server        server.Server      `yaml:"server"`
-	Database      model.Database     `yaml:"database"`
-	Authorization auth.Authorization `yaml:"Authorization"`
+	Server        server.Server       `yaml:"server"`
+	Database      model.Database      `yaml:"database"`
+	Authorization auth.Authorization  `yaml:"Authorization"`
+	Storage       storage.Storage     `yaml:"storage"`
+	Media         media.Media         `yaml:"media"`
+	Content       content.Content     `yaml:"content"`
+	Preview       preview.Preview     `yaml:"preview"`
+	GraphQL       controllers.GraphQL `yaml:"graphql"`
 }
 
 func ConfigLoad(path string) (Configuration, error) {
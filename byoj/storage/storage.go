@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var ErrNotFound = errors.New("object not found")
+var ErrInvalidKey = errors.New("invalid object key")
+
+// BlobStore 保存上传的媒体文件，key 由调用方生成，形如 "media/xxxx.png"
+type BlobStore interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+type Storage struct {
+	Driver string   `yaml:"driver"`
+	Local  LocalDir `yaml:"local"`
+	S3     S3Bucket `yaml:"s3"`
+}
+
+var store BlobStore
+
+func InitStorage(s Storage) error {
+	var err error
+	switch s.Driver {
+	case "", DriverLocal:
+		store, err = NewLocalStore(s.Local)
+	case DriverS3:
+		store, err = NewS3Store(s.S3)
+	default:
+		err = errors.New("unknown storage driver " + s.Driver)
+	}
+	return err
+}
+
+func GetStore() BlobStore {
+	return store
+}
+
+// key 只允许由字母、数字、'-'、'_'、'.' 组成的路径段，防止路径穿越
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return ErrInvalidKey
+	}
+	for _, seg := range strings.Split(key, "/") {
+		if seg == "" || seg == "." || seg == ".." {
+			return ErrInvalidKey
+		}
+		for _, r := range seg {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+				return ErrInvalidKey
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package tasks
+
+import (
+	"byoj/model"
+	"time"
+)
+
+const (
+	pollCloseInterval  = time.Minute
+	pollCloseBatchSize = 100
+)
+
+func init() {
+	register("poll", pollCloseInterval, closeDuePolls)
+}
+
+func closeDuePolls() error {
+	for {
+		closed, err := model.CloseDuePolls(time.Now(), pollCloseBatchSize)
+		if err != nil {
+			return err
+		}
+		if closed < pollCloseBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"byoj/model"
+	"byoj/preview"
+	"byoj/utils/logs"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	linkPreviewInterval  = 10 * time.Second
+	linkPreviewBatchSize = 20
+)
+
+func init() {
+	register("link_preview", linkPreviewInterval, fetchLinkPreviews)
+}
+
+func fetchLinkPreviews() error {
+	for {
+		previews, err := model.ClaimLinkPreviews(time.Now(), linkPreviewBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, p := range previews {
+			card, err := preview.Fetch(context.Background(), p.URL)
+			p.FetchedAt = time.Now()
+			if err != nil {
+				logs.Debug("Fetch link preview failed.", zap.String("url", p.URL), zap.Error(err))
+				p.Status = model.LinkPreviewFailed
+				p.Title, p.Description, p.ImageURL, p.SiteName = "", "", "", ""
+			} else {
+				p.Status = model.LinkPreviewOK
+				p.Title = card.Title
+				p.Description = card.Description
+				p.ImageURL = card.ImageURL
+				p.SiteName = card.SiteName
+			}
+			err = model.SaveLinkPreview(p)
+			if err != nil {
+				return err
+			}
+		}
+		if len(previews) < linkPreviewBatchSize {
+			return nil
+		}
+	}
+}
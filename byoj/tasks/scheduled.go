@@ -0,0 +1,27 @@
+package tasks
+
+import (
+	"byoj/model"
+	"time"
+)
+
+const (
+	scheduledPublishInterval  = 30 * time.Second
+	scheduledPublishBatchSize = 100
+)
+
+func init() {
+	register("scheduled", scheduledPublishInterval, publishScheduledPosts)
+}
+
+func publishScheduledPosts() error {
+	for {
+		published, err := model.PublishDueScheduledPosts(time.Now(), scheduledPublishBatchSize)
+		if err != nil {
+			return err
+		}
+		if published < scheduledPublishBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package tasks
+
+import (
+	"byoj/model"
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	suggestionRefreshInterval = time.Hour
+	suggestionHashtagWindow   = 30 * 24 * time.Hour
+	suggestionLimit           = 50
+	// 只为该时间内活跃的用户刷新，其他用户保留上次的推荐
+	suggestionActiveWindow = 7 * 24 * time.Hour
+	suggestionBatchSize    = 500
+)
+
+func init() {
+	register("suggestion", suggestionRefreshInterval, refreshSuggestions)
+}
+
+// 热度每轮只计算一次；单个用户计算失败不影响其他用户
+func refreshSuggestions() error {
+	err := model.RefreshUserPopularity()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	since := now.Add(-suggestionHashtagWindow)
+	activeSince := now.Add(-suggestionActiveWindow)
+	var afterID uint32
+	for {
+		userIDs, err := model.GetRecentlyActiveUserIDs(activeSince, afterID, suggestionBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			err = model.RefreshSuggestions(userID, since, suggestionLimit)
+			if err != nil {
+				logs.Warn("Refresh suggestions failed.", zap.Uint32("userID", userID), zap.Error(err))
+			}
+		}
+		if len(userIDs) < suggestionBatchSize {
+			return nil
+		}
+		afterID = userIDs[len(userIDs)-1]
+	}
+}
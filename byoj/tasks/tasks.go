@@ -0,0 +1,57 @@
+package tasks
+
+import (
+	"byoj/utils/logs"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type task struct {
+	name     string
+	interval time.Duration
+	run      func() error
+}
+
+var registered []task
+
+// 注册周期任务，需在 Start 前调用
+func register(name string, interval time.Duration, run func() error) {
+	registered = append(registered, task{
+		name:     name,
+		interval: interval,
+		run:      run,
+	})
+}
+
+// 启动所有后台任务，每个任务启动时立即执行一次，之后按间隔执行
+func Start() {
+	for _, t := range registered {
+		go loop(t)
+	}
+}
+
+func loop(t task) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		runOnce(t)
+		<-ticker.C
+	}
+}
+
+func runOnce(t task) {
+	defer func() {
+		if r := recover(); r != nil {
+			logs.Error("Task panicked.", zap.String("task", t.name), zap.Any("panic", r))
+		}
+	}()
+
+	start := time.Now()
+	err := t.run()
+	if err != nil {
+		logs.Warn("Task failed.", zap.String("task", t.name), zap.Error(err))
+		return
+	}
+	logs.Debug("Task finished.", zap.String("task", t.name), zap.Duration("elapsed", time.Since(start)))
+}
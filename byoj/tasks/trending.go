@@ -0,0 +1,86 @@
+package tasks
+
+import (
+	"byoj/model"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	trendingRefreshInterval = time.Minute
+	trendingHashtagLimit    = 20
+	trendingPostLimit       = 20
+)
+
+type TrendingWindow struct {
+	Name     string
+	Duration time.Duration
+	HalfLife time.Duration
+}
+
+var TrendingWindows = []TrendingWindow{
+	{Name: "1h", Duration: time.Hour, HalfLife: 15 * time.Minute},
+	{Name: "24h", Duration: 24 * time.Hour, HalfLife: 6 * time.Hour},
+}
+
+type Trending struct {
+	Hashtags  []model.HashtagScore
+	Posts     []model.Post
+	UpdatedAt time.Time
+}
+
+var (
+	trendingMutex sync.RWMutex
+	trendingCache = make(map[string]Trending)
+)
+
+var ErrTrendingNotReady = errors.New("trending has not been computed yet")
+
+func init() {
+	register("trending", trendingRefreshInterval, refreshTrending)
+}
+
+func refreshTrending() error {
+	now := time.Now()
+	for _, w := range TrendingWindows {
+		tags, err := model.GetTrendingHashtags(now, w.Duration, w.HalfLife, trendingHashtagLimit)
+		if err != nil {
+			return err
+		}
+
+		scores, err := model.GetTrendingPosts(now, w.Duration, w.HalfLife, trendingHashtagLimit, trendingPostLimit)
+		if err != nil {
+			return err
+		}
+		postIDs := make([]uint32, 0, len(scores))
+		for _, score := range scores {
+			postIDs = append(postIDs, score.PostID)
+		}
+		posts, err := model.FindPostsByIDs(postIDs)
+		if err != nil {
+			return err
+		}
+
+		trendingMutex.Lock()
+		trendingCache[w.Name] = Trending{
+			Hashtags:  tags,
+			Posts:     posts,
+			UpdatedAt: now,
+		}
+		trendingMutex.Unlock()
+	}
+	return nil
+}
+
+// 获取缓存的热门话题及帖子，window 为 TrendingWindows 中的 Name
+func GetTrending(window string) (Trending, error) {
+	trendingMutex.RLock()
+	defer trendingMutex.RUnlock()
+
+	trending, ok := trendingCache[window]
+	if !ok {
+		return Trending{}, ErrTrendingNotReady
+	}
+	return trending, nil
+}
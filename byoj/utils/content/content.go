@@ -0,0 +1,139 @@
+package content
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rivo/uniseg"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	RuleEmpty    = "empty"
+	RuleTooLarge = "too_large"
+	RuleTooLong  = "too_long"
+)
+
+// 链接无论实际长度，都按短链接长度计入字数
+const URLLength = 23
+
+type Content struct {
+	MaxLength int `yaml:"max-length"`
+	// 规范化之前原始内容的字节数上限，避免处理过大的请求
+	MaxBytes int `yaml:"max-bytes"`
+}
+
+var (
+	maxLength = 280
+	maxBytes  = 16 << 10
+)
+
+var urlRegexp = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+type RuleError struct {
+	Rule    string
+	Message string
+	Limit   int
+	Actual  int
+}
+
+func (e *RuleError) Error() string {
+	return e.Message
+}
+
+func InitContent(c Content) error {
+	if c.MaxLength > 0 {
+		maxLength = c.MaxLength
+	}
+	if c.MaxBytes > 0 {
+		maxBytes = c.MaxBytes
+	}
+	return nil
+}
+
+func MaxLength() int {
+	return maxLength
+}
+
+/**
+ * 校验并规范化帖子内容
+ * 依次进行：原始大小检查、NFC 规范化、去除控制字符、非空检查、字数检查
+ * @param: allowEmpty 是否允许空内容，如帖子带有媒体或投票
+ * @return: 规范化后的内容，校验失败时返回 *RuleError
+ **/
+func Validate(s string, allowEmpty bool) (string, *RuleError) {
+	if len(s) > maxBytes {
+		return "", &RuleError{
+			Rule:    RuleTooLarge,
+			Message: "Content is too large.",
+			Limit:   maxBytes,
+			Actual:  len(s),
+		}
+	}
+
+	s = Normalize(s)
+	if s == "" && !allowEmpty {
+		return "", &RuleError{
+			Rule:    RuleEmpty,
+			Message: "Content is empty.",
+		}
+	}
+
+	length := Length(s)
+	if length > maxLength {
+		return "", &RuleError{
+			Rule:    RuleTooLong,
+			Message: "Content is longer than " + strconv.Itoa(maxLength) + " characters.",
+			Limit:   maxLength,
+			Actual:  length,
+		}
+	}
+	return s, nil
+}
+
+// NFC 规范化，统一换行符，去除除换行和制表符外的控制字符及双向文本控制字符，并去除首尾空白
+func Normalize(s string) string {
+	s = strings.ToValidUTF8(s, "")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = norm.NFC.String(s)
+	s = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || isBidiControl(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+func isBidiControl(r rune) bool {
+	return (r >= '\u202a' && r <= '\u202e') || (r >= '\u2066' && r <= '\u2069') || r == '\u200e' || r == '\u200f'
+}
+
+// 按字素簇计算长度，每个链接计为 URLLength
+func Length(s string) int {
+	length := 0
+	last := 0
+	for _, loc := range FindURLs(s) {
+		length += uniseg.GraphemeClusterCount(s[last:loc[0]]) + URLLength
+		last = loc[1]
+	}
+	return length + uniseg.GraphemeClusterCount(s[last:])
+}
+
+// 返回内容中所有链接的起止字节位置，不含末尾的标点
+func FindURLs(s string) [][2]int {
+	var urls [][2]int
+	for _, loc := range urlRegexp.FindAllStringIndex(s, -1) {
+		end := loc[1]
+		for end > loc[0] && strings.ContainsRune(".,;:!?)]}'*`", rune(s[end-1])) {
+			end--
+		}
+		urls = append(urls, [2]int{loc[0], end})
+	}
+	return urls
+}
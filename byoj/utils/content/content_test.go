@@ -0,0 +1,52 @@
+package content_test
+
+import (
+	"byoj/utils/content"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	// e + 组合重音符规范化为单个 é，去除控制字符及双向覆盖字符
+	got := content.Normalize("  cafe\u0301\r\nhi\x00\u202ethere\t ")
+	want := "café\nhithere"
+	if got != want {
+		t.Fatalf("Normalize() = %q, want %q", got, want)
+	}
+}
+
+func TestLength(t *testing.T) {
+	cases := []struct {
+		s    string
+		want int
+	}{
+		{"hello", 5},
+		{"\U0001F468\u200d\U0001F469\u200d\U0001F467\U0001F1E8\U0001F1F3", 2},
+		{"see https://example.com/a/very/long/path?with=query.", 4 + content.URLLength + 1},
+		{"你好，世界", 5},
+	}
+	for _, c := range cases {
+		if got := content.Length(c.s); got != c.want {
+			t.Errorf("Length(%q) = %d, want %d", c.s, got, c.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if _, err := content.Validate(" \x01 ", false); err == nil || err.Rule != content.RuleEmpty {
+		t.Fatalf("Validate() error = %v, want rule %q", err, content.RuleEmpty)
+	}
+	if _, err := content.Validate("", true); err != nil {
+		t.Fatalf("Validate() with allowEmpty error = %v", err)
+	}
+
+	long := strings.Repeat("字", content.MaxLength()+1)
+	_, err := content.Validate(long, false)
+	if err == nil || err.Rule != content.RuleTooLong || err.Actual != content.MaxLength()+1 {
+		t.Fatalf("Validate() error = %+v, want rule %q", err, content.RuleTooLong)
+	}
+
+	if _, err := content.Validate(strings.Repeat("a", 1<<20), false); err == nil || err.Rule != content.RuleTooLarge {
+		t.Fatalf("Validate() error = %v, want rule %q", err, content.RuleTooLarge)
+	}
+}
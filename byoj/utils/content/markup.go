@@ -0,0 +1,271 @@
+package content
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	EntityURL     = "url"
+	EntityMention = "mention"
+	EntityHashtag = "hashtag"
+	EntityBold    = "bold"
+	EntityItalic  = "italic"
+	EntityCode    = "code"
+)
+
+// 可以用 '\' 转义的标记字符
+const escapableRunes = "\\*`[]@#"
+
+var linkURLRegexp = regexp.MustCompile(`^https?://[^\s<>"()]+$`)
+
+// Start、End 为实体在 Rendered.Text 中的 Unicode 码点下标，左闭右开
+type Entity struct {
+	Type  string `json:"type"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+	URL   string `json:"url,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type Rendered struct {
+	Text     string
+	Entities []Entity
+	HTML     string
+}
+
+type openFormat struct {
+	kind  string
+	tag   string
+	start int
+}
+
+type renderer struct {
+	src      []rune
+	text     []rune
+	html     strings.Builder
+	entities []Entity
+	open     []openFormat
+	urls     map[int]int
+}
+
+/**
+ * 解析帖子内容中的受限标记，生成纯文本、实体列表及安全的 HTML
+ * 支持：链接（自动识别或 [文字](链接)，仅限 http/https）、@提及、#话题、**粗体**、*斜体*、`代码`
+ * 除以上标记生成的标签外，所有内容都会被转义
+ **/
+func Render(s string) Rendered {
+	r := &renderer{
+		src:  []rune(s),
+		urls: make(map[int]int),
+	}
+	// 预先计算链接位置，将字节下标转为码点下标
+	for _, loc := range FindURLs(s) {
+		start := len([]rune(s[:loc[0]]))
+		r.urls[start] = start + len([]rune(s[loc[0]:loc[1]]))
+	}
+	r.run()
+
+	// 按起始位置排序，范围相同时外层的格式实体在前
+	sort.SliceStable(r.entities, func(i, j int) bool {
+		a, b := r.entities[i], r.entities[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		if a.End != b.End {
+			return a.End > b.End
+		}
+		return isFormat(a.Type) && !isFormat(b.Type)
+	})
+	if r.entities == nil {
+		r.entities = make([]Entity, 0)
+	}
+	return Rendered{
+		Text:     string(r.text),
+		Entities: r.entities,
+		HTML:     r.html.String(),
+	}
+}
+
+func (r *renderer) run() {
+	src := r.src
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\\' && i+1 < len(src) && strings.ContainsRune(escapableRunes, src[i+1]):
+			r.writeText(src[i+1])
+			i += 2
+		case c == '`':
+			i = r.code(i)
+		case c == '*' && i+1 < len(src) && src[i+1] == '*':
+			i = r.format(i, "**", EntityBold, "strong")
+		case c == '*':
+			i = r.format(i, "*", EntityItalic, "em")
+		case c == '[':
+			i = r.link(i)
+		case r.urls[i] > i:
+			end := r.urls[i]
+			u := string(src[i:end])
+			r.writeEntity(Entity{Type: EntityURL, URL: u}, src[i:end], `<a href="`+html.EscapeString(u)+`" rel="nofollow noopener noreferrer" target="_blank">`)
+			i = end
+		case (c == '@' || c == '#') && (i == 0 || !isWordRune(src[i-1])):
+			i = r.tag(i)
+		default:
+			r.writeText(c)
+			i++
+		}
+	}
+	for len(r.open) > 0 {
+		r.closeTop()
+	}
+}
+
+func (r *renderer) writeText(c rune) {
+	r.text = append(r.text, c)
+	if c == '\n' {
+		r.html.WriteString("<br>")
+		return
+	}
+	r.html.WriteString(html.EscapeString(string(c)))
+}
+
+// 写入不含嵌套标记的实体，openTag 为对应的 HTML 开始标签
+func (r *renderer) writeEntity(e Entity, content []rune, openTag string) {
+	e.Start = len(r.text)
+	r.html.WriteString(openTag)
+	for _, c := range content {
+		r.writeText(c)
+	}
+	if strings.HasPrefix(openTag, "<code") {
+		r.html.WriteString("</code>")
+	} else {
+		r.html.WriteString("</a>")
+	}
+	e.End = len(r.text)
+	r.entities = append(r.entities, e)
+}
+
+func (r *renderer) code(i int) int {
+	end := indexRune(r.src, i+1, '`')
+	if end <= i+1 {
+		r.writeText('`')
+		return i + 1
+	}
+	r.writeEntity(Entity{Type: EntityCode}, r.src[i+1:end], "<code>")
+	return end + 1
+}
+
+// 粗体、斜体可以互相嵌套，遇到外层结束标记时会同时结束未闭合的内层格式
+func (r *renderer) format(i int, marker string, kind string, tag string) int {
+	n := len([]rune(marker))
+	for k := len(r.open) - 1; k >= 0; k-- {
+		if r.open[k].kind == kind {
+			for len(r.open) > k {
+				r.closeTop()
+			}
+			return i + n
+		}
+	}
+	if !r.canOpen(i, marker) {
+		for _, c := range marker {
+			r.writeText(c)
+		}
+		return i + n
+	}
+	r.open = append(r.open, openFormat{kind: kind, tag: tag, start: len(r.text)})
+	r.html.WriteString("<" + tag + ">")
+	return i + n
+}
+
+// 开始标记后须紧跟非空白字符，且之后存在前一个字符为非空白的未转义结束标记
+func (r *renderer) canOpen(i int, marker string) bool {
+	m := []rune(marker)
+	from := i + len(m)
+	if from >= len(r.src) || unicode.IsSpace(r.src[from]) {
+		return false
+	}
+	for k := from + 1; k+len(m) <= len(r.src); k++ {
+		if r.src[k-1] == '\\' {
+			continue
+		}
+		if string(r.src[k:k+len(m)]) == marker && !unicode.IsSpace(r.src[k-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *renderer) closeTop() {
+	top := r.open[len(r.open)-1]
+	r.open = r.open[:len(r.open)-1]
+	r.html.WriteString("</" + top.tag + ">")
+	if len(r.text) > top.start {
+		r.entities = append(r.entities, Entity{Type: top.kind, Start: top.start, End: len(r.text)})
+	}
+}
+
+// [文字](链接)，格式不正确或链接不是 http/https 时按普通文本处理
+func (r *renderer) link(i int) int {
+	closeText := indexRune(r.src, i+1, ']')
+	if closeText > i+1 && closeText+1 < len(r.src) && r.src[closeText+1] == '(' {
+		closeURL := indexRune(r.src, closeText+2, ')')
+		if closeURL > closeText+2 {
+			text := r.src[i+1 : closeText]
+			u := string(r.src[closeText+2 : closeURL])
+			if linkURLRegexp.MatchString(u) && !containsRune(text, '\n') {
+				if _, err := url.Parse(u); err == nil {
+					r.writeEntity(Entity{Type: EntityURL, URL: u}, text, `<a href="`+html.EscapeString(u)+`" rel="nofollow noopener noreferrer" target="_blank">`)
+					return closeURL + 1
+				}
+			}
+		}
+	}
+	r.writeText('[')
+	return i + 1
+}
+
+func (r *renderer) tag(i int) int {
+	end := i + 1
+	for end < len(r.src) && isWordRune(r.src[end]) {
+		end++
+	}
+	if end == i+1 {
+		r.writeText(r.src[i])
+		return i + 1
+	}
+
+	value := string(r.src[i+1 : end])
+	if r.src[i] == '@' {
+		r.writeEntity(Entity{Type: EntityMention, Value: value}, r.src[i:end],
+			`<a class="mention" href="/v1/user?user_name=`+url.QueryEscape(value)+`">`)
+	} else {
+		r.writeEntity(Entity{Type: EntityHashtag, Value: strings.ToLower(value)}, r.src[i:end],
+			`<a class="hashtag" href="/v1/search?q=`+url.QueryEscape("#"+value)+`">`)
+	}
+	return end
+}
+
+func isFormat(kind string) bool {
+	return kind == EntityBold || kind == EntityItalic
+}
+
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+func indexRune(rs []rune, from int, c rune) int {
+	for i := from; i < len(rs); i++ {
+		if rs[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+func containsRune(rs []rune, c rune) bool {
+	return indexRune(rs, 0, c) >= 0
+}
@@ -0,0 +1,65 @@
+package content_test
+
+import (
+	"byoj/utils/content"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	got := content.Render("**hi** @ligen131, see *[docs](https://a.com/x)* #Go `<b>`\nhttps://b.com.")
+
+	wantText := "hi @ligen131, see docs #Go <b>\nhttps://b.com."
+	if got.Text != wantText {
+		t.Fatalf("Text = %q, want %q", got.Text, wantText)
+	}
+
+	wantEntities := []content.Entity{
+		{Type: content.EntityBold, Start: 0, End: 2},
+		{Type: content.EntityMention, Start: 3, End: 12, Value: "ligen131"},
+		{Type: content.EntityItalic, Start: 18, End: 22},
+		{Type: content.EntityURL, Start: 18, End: 22, URL: "https://a.com/x"},
+		{Type: content.EntityHashtag, Start: 23, End: 26, Value: "go"},
+		{Type: content.EntityCode, Start: 27, End: 30},
+		{Type: content.EntityURL, Start: 31, End: 44, URL: "https://b.com"},
+	}
+	if !reflect.DeepEqual(got.Entities, wantEntities) {
+		t.Fatalf("Entities = %+v, want %+v", got.Entities, wantEntities)
+	}
+
+	wantHTML := `<strong>hi</strong> <a class="mention" href="/v1/user?user_name=ligen131">@ligen131</a>, see ` +
+		`<em><a href="https://a.com/x" rel="nofollow noopener noreferrer" target="_blank">docs</a></em> ` +
+		`<a class="hashtag" href="/v1/search?q=%23Go">#Go</a> <code>&lt;b&gt;</code><br>` +
+		`<a href="https://b.com" rel="nofollow noopener noreferrer" target="_blank">https://b.com</a>.`
+	if got.HTML != wantHTML {
+		t.Fatalf("HTML = %q, want %q", got.HTML, wantHTML)
+	}
+}
+
+func TestRenderUnclosedAndEscaped(t *testing.T) {
+	got := content.Render(`2 * 3 = 6, \*not italic\*, **open`)
+	if got.Text != "2 * 3 = 6, *not italic*, **open" || len(got.Entities) != 0 {
+		t.Fatalf("Render() = %+v", got)
+	}
+}
+
+func TestRenderSanitizes(t *testing.T) {
+	inputs := []string{
+		`<script>alert(1)</script>`,
+		`[click](javascript:alert(1))`,
+		`[x](https://a.com/"onmouseover="alert(1))`,
+		`https://a.com/"><img src=x onerror=alert(1)>`,
+		"`</code><script>alert(1)</script>`",
+		`@"><svg/onload=alert(1)>`,
+		`**<img src=x onerror=alert(1)>**`,
+	}
+	for _, input := range inputs {
+		got := content.Render(input).HTML
+		for _, bad := range []string{"<script", "<img", "<svg", `href="javascript`, `"onmouseover`, `" onerror`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Render(%q).HTML = %q contains %q", input, got, bad)
+			}
+		}
+	}
+}
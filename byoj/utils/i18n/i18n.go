@@ -0,0 +1,142 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+/**
+ * 面向用户的文本目录，每种语言一个 locales/<lang>.json，键为 "error.not_found" 形式的扁平字符串
+ * 文本按 fmt 格式化，参数使用带下标的占位符，如 "%[1]s must be at most %[2]v characters."
+ * 新增文本时需在每种语言中补充同一个键
+ **/
+
+// 默认语言，请求的语言不受支持或目录中缺少某个键时使用
+const Default = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogues = loadCatalogues()
+	locales    = sortedLocales()
+	matcher    = newMatcher()
+)
+
+func loadCatalogues() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	result := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		data, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: parse %s: %v", entry.Name(), err))
+		}
+		result[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	if _, ok := result[Default]; !ok {
+		panic("i18n: missing default locale " + Default)
+	}
+	return result
+}
+
+// 默认语言排在首位，作为协商失败时的结果
+func sortedLocales() []string {
+	result := []string{Default}
+	for lang := range catalogues {
+		if lang != Default {
+			result = append(result, lang)
+		}
+	}
+	sort.Strings(result[1:])
+	return result
+}
+
+func newMatcher() language.Matcher {
+	tags := make([]language.Tag, 0, len(locales))
+	for _, lang := range locales {
+		tags = append(tags, language.MustParse(lang))
+	}
+	return language.NewMatcher(tags)
+}
+
+// 支持的语言，默认语言在首位
+func Locales() []string {
+	return append([]string(nil), locales...)
+}
+
+// 某种语言目录中的全部键，已排序
+func Keys(lang string) []string {
+	messages := catalogues[lang]
+	keys := make([]string, 0, len(messages))
+	for key := range messages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+/**
+ * 按 Accept-Language 请求头选择支持的语言
+ * 如 "zh-CN,zh;q=0.9,en;q=0.8" 返回 "zh"，请求头为空、无法解析或没有匹配的语言时返回默认语言
+ **/
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return Default
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return Default
+	}
+	return locales[index]
+}
+
+/**
+ * 翻译文本
+ * 语言中缺少该键时使用默认语言，默认语言中也没有时返回键本身
+ * @param: args 按顺序填入占位符，文本没有占位符时忽略
+ **/
+func Translate(lang string, key string, args ...interface{}) string {
+	message, ok := catalogues[lang][key]
+	if !ok {
+		message, ok = catalogues[Default][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 || !strings.Contains(message, "%") {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+// 可翻译的错误，Error 返回默认语言的文本，用于日志
+type Error struct {
+	Key  string
+	Args []interface{}
+}
+
+func NewError(key string, args ...interface{}) *Error {
+	return &Error{Key: key, Args: args}
+}
+
+func (e *Error) Error() string {
+	return e.Translate(Default)
+}
+
+func (e *Error) Translate(lang string) string {
+	return Translate(lang, e.Key, e.Args...)
+}
@@ -0,0 +1,82 @@
+package i18n_test
+
+import (
+	"byoj/utils/i18n"
+	"reflect"
+	"regexp"
+	"sort"
+	"testing"
+)
+
+var placeholder = regexp.MustCompile(`%\[\d+\][a-z]`)
+
+func placeholders(message string) []string {
+	found := placeholder.FindAllString(message, -1)
+	sort.Strings(found)
+	return found
+}
+
+// 每个键在每种语言中都存在，且占位符一致
+func TestCataloguesComplete(t *testing.T) {
+	locales := i18n.Locales()
+	if len(locales) < 2 || locales[0] != i18n.Default {
+		t.Fatalf("Locales() = %v, want default locale first and at least two locales", locales)
+	}
+
+	all := make(map[string]bool)
+	for _, lang := range locales {
+		for _, key := range i18n.Keys(lang) {
+			all[key] = true
+		}
+	}
+	for _, lang := range locales {
+		keys := make(map[string]bool)
+		for _, key := range i18n.Keys(lang) {
+			keys[key] = true
+		}
+		for key := range all {
+			if !keys[key] {
+				t.Errorf("locale %q is missing key %q", lang, key)
+				continue
+			}
+			got := placeholders(i18n.Translate(lang, key))
+			want := placeholders(i18n.Translate(i18n.Default, key))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("locale %q key %q placeholders = %v, want %v", lang, key, got, want)
+			}
+		}
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	cases := []struct {
+		header string
+		want   string
+	}{
+		{"", "en"},
+		{"zh-CN,zh;q=0.9,en;q=0.8", "zh"},
+		{"zh-Hant-TW", "zh"},
+		{"en-US,en;q=0.9", "en"},
+		{"fr-FR, de;q=0.5", "en"},
+		{"fr;q=0.9, zh;q=0.5", "zh"},
+		{";;invalid", "en"},
+	}
+	for _, c := range cases {
+		if got := i18n.Negotiate(c.header); got != c.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", c.header, got, c.want)
+		}
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	if got := i18n.Translate("zh", "param.required", "user_name"); got != "user_name 为必填项。" {
+		t.Errorf("Translate(zh) = %q", got)
+	}
+	// 不支持的语言回退到默认语言，不存在的键返回键本身
+	if got := i18n.Translate("fr", "param.required", "user_name"); got != "user_name is required." {
+		t.Errorf("Translate(fr) = %q", got)
+	}
+	if got := i18n.Translate("zh", "no.such.key"); got != "no.such.key" {
+		t.Errorf("Translate(missing) = %q", got)
+	}
+}